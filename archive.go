@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const archiveBatchSize = 100
+const likesRefreshWindow = 7 * 24 * time.Hour //likes on anything newer than this get re-fetched every sync
+
+//messageArchive keeps each group's message history in its storage so a run only fetches what changed
+type messageArchive struct {
+	storage    archiveStorage
+	mutex      sync.Mutex
+	groupLocks map[string]*sync.Mutex //one per group so groups can sync at the same time
+	loaded     map[string]loadedGroup //the last version of each group's file read or written, so it's only read again when it changes
+}
+
+//archivedGroup is the file kept for one group, newest message first
+type archivedGroup struct {
+	Messages []*Message `json:"messages"`
+}
+
+type loadedGroup struct {
+	version  string
+	archived archivedGroup
+}
+
+var archive *messageArchive
+
+//newMessageArchive keeps the archive in files in dir
+func newMessageArchive(dir string) *messageArchive {
+	return newArchive(fileArchiveStorage{dir: dir})
+}
+
+func newArchive(storage archiveStorage) *messageArchive {
+	return &messageArchive{storage: storage, groupLocks: make(map[string]*sync.Mutex), loaded: make(map[string]loadedGroup)}
+}
+
+//lock returns the mutex guarding groupID's file, making it on first use
+func (archive *messageArchive) lock(groupID string) *sync.Mutex {
+	archive.mutex.Lock()
+	defer archive.mutex.Unlock()
+	groupLock, ok := archive.groupLocks[groupID]
+	if !ok {
+		groupLock = &sync.Mutex{}
+		archive.groupLocks[groupID] = groupLock
+	}
+	return groupLock
+}
+
+//exists is true once the group has been synced at least once
+func (archive *messageArchive) exists(groupID string) bool {
+	_, err := archive.storage.version(groupID)
+	return err == nil
+}
+
+//version changes every time the group's archive is saved, "" if it hasn't been
+func (archive *messageArchive) version(groupID string) string {
+	version, _ := archive.storage.version(groupID)
+	return version
+}
+
+//load returns the group's archive, with messages the caller is free to change
+func (archive *messageArchive) load(groupID string) archivedGroup {
+	version, err := archive.storage.version(groupID)
+	if errors.Is(err, errArchiveMissing) {
+		return archivedGroup{}
+	}
+	if err != nil {
+		log.Print(fmt.Sprintf("Error reached when checking the archive for group %s, starting over.", groupID))
+		log.Print(err)
+		return archivedGroup{}
+	}
+	archive.mutex.Lock()
+	loaded, ok := archive.loaded[groupID]
+	archive.mutex.Unlock()
+	if !ok || loaded.version != version {
+		body, err := archive.storage.read(groupID)
+		if err != nil {
+			log.Print(fmt.Sprintf("Error reached when reading the archive for group %s, starting over.", groupID))
+			log.Print(err)
+			return archivedGroup{}
+		}
+		loaded = loadedGroup{version: version}
+		err = json.Unmarshal(body, &loaded.archived)
+		if err != nil {
+			log.Print(fmt.Sprintf("Error reached when unmarshalling the archive for group %s, starting over.", groupID))
+			log.Print(err)
+			return archivedGroup{}
+		}
+		archive.remember(groupID, loaded)
+	}
+	return loaded.archived.copy()
+}
+
+func (archive *messageArchive) save(groupID string, archived archivedGroup) error {
+	body, err := json.Marshal(archived)
+	if err != nil {
+		return err
+	}
+	err = archive.storage.write(groupID, body)
+	if err != nil {
+		return err
+	}
+	if version, err := archive.storage.version(groupID); err == nil {
+		archive.remember(groupID, loadedGroup{version: version, archived: archived.copy()})
+	}
+	return nil
+}
+
+func (archive *messageArchive) remember(groupID string, loaded loadedGroup) {
+	archive.mutex.Lock()
+	defer archive.mutex.Unlock()
+	archive.loaded[groupID] = loaded
+}
+
+func (archived archivedGroup) copy() archivedGroup {
+	copied := archivedGroup{Messages: make([]*Message, 0, len(archived.Messages))}
+	for _, message := range archived.Messages {
+		messageCopy := *message
+		copied.Messages = append(copied.Messages, &messageCopy)
+	}
+	return copied
+}
+
+//sync pages back from the newest message to the archived messages whose likes have settled, saving nothing if a request fails
+func (archive *messageArchive) sync(groupID string) error {
+	groupLock := archive.lock(groupID)
+	groupLock.Lock()
+	defer groupLock.Unlock()
+	archived := archive.load(groupID)
+	byID := make(map[string]*Message)
+	wasArchived := make(map[string]bool)
+	for _, message := range archived.Messages {
+		byID[message.MessageID] = message
+		wasArchived[message.MessageID] = true
+	}
+	cutoff := time.Now().Add(-likesRefreshWindow).Unix()
+	numNew := 0
+	beforeID := ""
+	for {
+		messagesBatch, err := groupMe.getMessageBatch(groupID, beforeID, "", archiveBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(messagesBatch) == 0 {
+			break
+		}
+		for _, message := range messagesBatch {
+			if !wasArchived[message.MessageID] {
+				numNew++
+			}
+			byID[message.MessageID] = message
+		}
+		lastMessage := messagesBatch[len(messagesBatch)-1]
+		if wasArchived[lastMessage.MessageID] && lastMessage.TimeSent < cutoff {
+			break
+		}
+		beforeID = lastMessage.MessageID
+	}
+	archived.Messages = nil
+	for _, message := range byID {
+		archived.Messages = append(archived.Messages, message)
+	}
+	sort.Slice(archived.Messages, func(i, j int) bool {
+		return archived.Messages[i].TimeSent > archived.Messages[j].TimeSent
+	})
+	err := archive.save(groupID, archived)
+	if err != nil {
+		return fmt.Errorf("saving the archive for group %s: %w", groupID, err)
+	}
+	log.Print(fmt.Sprintf("Archived %d new messages for group %s, %d total.", numNew, groupID, len(archived.Messages)))
+	return nil
+}
+
+//messagesFromDate returns copies of every archived message sent on month/day of any year in loc, newest first
+func (archive *messageArchive) messagesFromDate(groupID string, loc *time.Location, month time.Month, day int) []*Message {
+	groupLock := archive.lock(groupID)
+	groupLock.Lock()
+	defer groupLock.Unlock()
+	var messages []*Message
+	for _, message := range archive.load(groupID).Messages {
+		_, messageMonth, messageDay := time.Unix(message.TimeSent, 0).In(loc).Date()
+		if messageMonth == month && messageDay == day {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+//allMessages returns copies of every archived message, newest first
+func (archive *messageArchive) allMessages(groupID string) []*Message {
+	groupLock := archive.lock(groupID)
+	groupLock.Lock()
+	defer groupLock.Unlock()
+	return archive.load(groupID).Messages
+}
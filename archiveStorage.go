@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var errArchiveMissing = errors.New("no archive for the group yet")
+
+//archiveStorage is where the archive keeps each group's file. version changes every time the file is written, so
+//a copy already in memory can be reused without reading the whole file again
+type archiveStorage interface {
+	read(groupID string) ([]byte, error)
+	write(groupID string, body []byte) error
+	version(groupID string) (string, error) //errArchiveMissing if the group has no file
+}
+
+//newArchiveStorage picks the archive's storage: a local directory, or an S3 bucket so it outlasts Lambda containers
+func newArchiveStorage(backend, location, bucket, region string) (archiveStorage, error) {
+	switch backend {
+	case "file":
+		return fileArchiveStorage{dir: location}, nil
+	case "s3":
+		return &s3ArchiveStorage{bucket: bucket, prefix: location, region: region}, nil
+	}
+	return nil, fmt.Errorf("unknown archive backend %q", backend)
+}
+
+//fileArchiveStorage keeps one JSON file per group in dir
+type fileArchiveStorage struct {
+	dir string
+}
+
+func (storage fileArchiveStorage) path(groupID string) string {
+	return filepath.Join(storage.dir, groupID+".json")
+}
+
+func (storage fileArchiveStorage) read(groupID string) ([]byte, error) {
+	body, err := ioutil.ReadFile(storage.path(groupID))
+	if os.IsNotExist(err) {
+		return nil, errArchiveMissing
+	}
+	return body, err
+}
+
+func (storage fileArchiveStorage) write(groupID string, body []byte) error {
+	err := os.MkdirAll(storage.dir, 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(storage.path(groupID), body, 0644)
+}
+
+func (storage fileArchiveStorage) version(groupID string) (string, error) {
+	info, err := os.Stat(storage.path(groupID))
+	if os.IsNotExist(err) {
+		return "", errArchiveMissing
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+//s3ArchiveStorage keeps one JSON object per group in bucket, under prefix
+type s3ArchiveStorage struct {
+	client      *s3.S3
+	sessionOnce sync.Once
+	sessionErr  error
+	bucket      string
+	prefix      string
+	region      string
+}
+
+//startSession only connects the first time it's called, like the DynamoDB store
+func (storage *s3ArchiveStorage) startSession() error {
+	storage.sessionOnce.Do(func() {
+		session, err := session.NewSession(&aws.Config{Region: aws.String(storage.region)})
+		if err != nil {
+			storage.sessionErr = err
+			return
+		}
+		storage.client = s3.New(session)
+	})
+	return storage.sessionErr
+}
+
+func (storage *s3ArchiveStorage) key(groupID string) *string {
+	return aws.String(path.Join(storage.prefix, groupID+".json"))
+}
+
+//missing is true for the errors S3 gives for an object that isn't there
+func missing(err error) bool {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound"
+	}
+	return false
+}
+
+func (storage *s3ArchiveStorage) read(groupID string) ([]byte, error) {
+	if err := storage.startSession(); err != nil {
+		return nil, err
+	}
+	output, err := storage.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(storage.bucket), Key: storage.key(groupID)})
+	if missing(err) {
+		return nil, errArchiveMissing
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	return ioutil.ReadAll(output.Body)
+}
+
+func (storage *s3ArchiveStorage) write(groupID string, body []byte) error {
+	if err := storage.startSession(); err != nil {
+		return err
+	}
+	_, err := storage.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(storage.bucket),
+		Key:         storage.key(groupID),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (storage *s3ArchiveStorage) version(groupID string) (string, error) {
+	if err := storage.startSession(); err != nil {
+		return "", err
+	}
+	output, err := storage.client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(storage.bucket), Key: storage.key(groupID)})
+	if missing(err) {
+		return "", errArchiveMissing
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.ETag), nil
+}
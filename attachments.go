@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+//coordinate is a location attachment's lat or lng. GroupMe sends them as strings, but a number is read just as well
+type coordinate string
+
+func (value *coordinate) UnmarshalJSON(body []byte) error {
+	*value = coordinate(strings.Trim(string(body), "\""))
+	return nil
+}
+
+//repostAttachments is what a bot can post of a message's attachments, plus a line of text for each one it can't.
+//offset is how many characters the repost puts before the original text, like its opening quote, so mentions are moved over to stay on their names
+func repostAttachments(attachments []Attachment, offset int) ([]Attachment, []string) {
+	var postable []Attachment
+	var fallbacks []string
+	for _, attachment := range attachments {
+		switch attachment.Type {
+		case "image", "location", "emoji", "reply":
+			postable = append(postable, attachment)
+		case "mentions":
+			mentions := attachment
+			mentions.Loci = nil
+			for _, locus := range attachment.Loci {
+				if len(locus) != 2 {
+					continue
+				}
+				mentions.Loci = append(mentions.Loci, []int{locus[0] + offset, locus[1]})
+			}
+			postable = append(postable, mentions)
+		default: //videos, files, polls, events and anything newer can't come from a bot
+			fallbacks = append(fallbacks, attachmentFallback(attachment))
+		}
+	}
+	return postable, fallbacks
+}
+
+//attachmentFallback links to an attachment a bot can't post, or names it when there's nothing to link to
+func attachmentFallback(attachment Attachment) string {
+	if attachment.URL != "" {
+		return fmt.Sprintf("[%s] %s", attachment.Type, attachment.URL)
+	}
+	return fmt.Sprintf("[%s]", attachment.Type)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+const defaultAuthorPenaltyDays = 30
+
+//authorKey is who sent the message, by user id when GroupMe gave one
+func authorKey(message Message) string {
+	if message.SenderID != "" {
+		return message.SenderID
+	}
+	return message.Name
+}
+
+func repostAuthorKey(repost dbConnection.Repost) string {
+	if repost.AuthorId != "" {
+		return repost.AuthorId
+	}
+	return repost.AuthorName
+}
+
+//authorHistory is when each author was last reposted and how often lately, for spreading memories around
+type authorHistory struct {
+	lastReposted   map[string]time.Time
+	recentReposts  map[string]int //within the penalty window
+	penaltyWindow  time.Duration
+	cooldownWindow time.Duration
+}
+
+func newAuthorHistory(rules dbConnection.SelectionRules, reposts []dbConnection.Repost, now time.Time) *authorHistory {
+	penaltyDays := rules.AuthorPenaltyDays
+	if penaltyDays < 1 {
+		penaltyDays = defaultAuthorPenaltyDays
+	}
+	history := &authorHistory{
+		lastReposted:   make(map[string]time.Time),
+		recentReposts:  make(map[string]int),
+		penaltyWindow:  time.Duration(penaltyDays) * 24 * time.Hour,
+		cooldownWindow: time.Duration(rules.AuthorCooldownDays) * 24 * time.Hour,
+	}
+	for _, repost := range reposts {
+		history.add(repostAuthorKey(repost), time.Unix(repost.RepostedAt, 0), now)
+	}
+	return history
+}
+
+func (history *authorHistory) add(author string, repostedAt, now time.Time) {
+	if repostedAt.After(history.lastReposted[author]) {
+		history.lastReposted[author] = repostedAt
+	}
+	if now.Sub(repostedAt) < history.penaltyWindow {
+		history.recentReposts[author]++
+	}
+}
+
+//coolingDown is true if the author was reposted too recently to be picked again
+func (history *authorHistory) coolingDown(author string, now time.Time) bool {
+	lastReposted, ok := history.lastReposted[author]
+	return ok && history.cooldownWindow > 0 && now.Sub(lastReposted) < history.cooldownWindow
+}
+
+//penalize scales every weight down by penalty for each time its author was reposted in the penalty window, and zeroes
+//authors in their cooldown. If that would leave nothing to pick, the cooldown is ignored
+func (history *authorHistory) penalize(messages []Message, weights []float64, penalty float64, now time.Time) {
+	if penalty > 0 {
+		for i, message := range messages {
+			weights[i] *= math.Pow(1-math.Min(penalty, 1), float64(history.recentReposts[authorKey(message)]))
+		}
+	}
+	cooled := make([]float64, len(weights))
+	anyLeft := false
+	for i, message := range messages {
+		if !history.coolingDown(authorKey(message), now) {
+			cooled[i] = weights[i]
+			anyLeft = anyLeft || weights[i] > 0
+		}
+	}
+	if anyLeft {
+		copy(weights, cooled)
+	}
+}
+
+//authorFeature is one line of the author report
+type authorFeature struct {
+	Author       string `json:"author"`
+	AuthorID     string `json:"author_id,omitempty"`
+	Reposts      int    `json:"reposts"`
+	Likes        int    `json:"likes"` //on the reposted messages
+	LastReposted string `json:"last_reposted,omitempty"`
+}
+
+//authorReport is how many times each member, and anyone who has left, has been reposted in the group, most first
+func authorReport(group Group) []authorFeature {
+	loc := groupSettings(group.GroupID).Loc()
+	byAuthor := make(map[string]*authorFeature)
+	var order []string
+	feature := func(key, name, id string) *authorFeature {
+		if _, ok := byAuthor[key]; !ok {
+			byAuthor[key] = &authorFeature{Author: name, AuthorID: id}
+			order = append(order, key)
+		}
+		return byAuthor[key]
+	}
+	for _, member := range group.Members {
+		fields, ok := member.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := fields["nickname"].(string)
+		id, _ := fields["user_id"].(string)
+		key := id
+		if key == "" {
+			key = name
+		}
+		feature(key, name, id)
+	}
+	lastReposted := make(map[string]int64)
+	for _, repost := range store.GetReposts(group.GroupID) {
+		key := repostAuthorKey(repost)
+		author := feature(key, repost.AuthorName, repost.AuthorId)
+		author.Reposts++
+		author.Likes += repost.Likes
+		if repost.RepostedAt > lastReposted[key] {
+			lastReposted[key] = repost.RepostedAt
+			author.LastReposted = time.Unix(repost.RepostedAt, 0).In(loc).Format("2006-01-02")
+		}
+	}
+	report := []authorFeature{}
+	for _, key := range order {
+		report = append(report, *byAuthor[key])
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].Reposts > report[j].Reposts
+	})
+	return report
+}
+
+func describeAuthorRules(rules dbConnection.SelectionRules) string {
+	description := ""
+	if rules.AuthorPenalty > 0 {
+		penaltyDays := rules.AuthorPenaltyDays
+		if penaltyDays < 1 {
+			penaltyDays = defaultAuthorPenaltyDays
+		}
+		description += fmt.Sprintf(", %g%% less likely per repost in %d days", rules.AuthorPenalty*100, penaltyDays)
+	}
+	if rules.AuthorCooldownDays > 0 {
+		description += fmt.Sprintf(", %d day cooldown per author", rules.AuthorCooldownDays)
+	}
+	return description
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gin-gonic/gin"
+)
+
+//parseCallback unmarshals the message GroupMe sends to the bot's callback_url
+func parseCallback(body []byte) (Message, error) {
+	message := Message{}
+	err := json.Unmarshal(body, &message)
+	if err != nil {
+		return message, err
+	}
+	if message.GroupID == "" {
+		return message, fmt.Errorf("callback payload has no group_id")
+	}
+	return message, nil
+}
+
+func handleCallback(body []byte) error {
+	message, err := parseCallback(body)
+	if err != nil {
+		log.Print("Error reached when parsing callback.")
+		log.Print(err)
+		return err
+	}
+	log.Print(fmt.Sprintf("Got callback for message %s from %s in group %s.", message.MessageID, message.Name, message.GroupID))
+	routeCommand(message)
+	return nil
+}
+
+//callbackHandler is the Lambda entry point when the function sits behind API Gateway
+func callbackHandler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	err := handleCallback([]byte(request.Body))
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest}, nil
+	}
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+}
+
+func runCallbackServer(port string) {
+	router := gin.Default()
+	router.POST("/callback", func(c *gin.Context) {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		err = handleCallback(body)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	err := router.Run(":" + port)
+	if err != nil {
+		log.Print("Fatal error reached when running callback server.")
+		log.Fatalln(err)
+	}
+}
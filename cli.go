@@ -0,0 +1,747 @@
+package main
+
+import (
+	"GroupMeChatBot/dbConnection"
+	"GroupMeChatBot/scheduler"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const exitOK = 0
+const exitFailure = 1
+const exitUsage = 2
+
+const cliUsage = `Usage: main [flags] <command> [options]
+
+Commands:
+  groups list                          list the groups the access token is in
+  bot list                             list the groups that have the bot
+  bot add --group <id or name>         add the bot to a group
+  bot remove --group <id or name>      remove the bot from a group
+  bot timezone --group <id or name> --zone <IANA zone or ->
+                                       set the time zone a group's dates are in, - for the configured one
+  run [--group <id or name>] [--date YYYY-MM-DD | --from YYYY-MM-DD --to YYYY-MM-DD] [--dry-run]
+                                       post memories now, or for other dates
+  digest [--group <id or name>] [--from YYYY-MM-DD] [--days 7] [--dry-run]
+                                       post one message with a memory from each day
+  bot selection --group <id or name> --strategy lottery|top|years|authors [--top-n 3] [--seed N]
+                [--count 1] [--fallback none|nearest|top|note]
+                [--author-penalty 0.5] [--author-penalty-days 30] [--author-cooldown-days N]
+                                       choose how a group's memory is picked
+  bot commands --group <id or name> [--enable name,...] [--disable name,...]
+                                       list a group's chat commands, turning some on or off
+  search --group <id or name> --query <terms> [--limit 10] [--offline]
+                                       search a group's archived messages, without GroupMe if --offline
+  stats --group <id or name> [--from YYYY-MM-DD] [--to YYYY-MM-DD] [--offline]
+                                       likes given and received, counts and best times for each member
+  authors --group <id or name>         show how often each member has been reposted
+  schedule [--seed N] [--dry-run]      schedule every group's next run, or just show when it would be
+
+Every command takes --format table or --format json.`
+
+var errUsage = errors.New("usage")
+
+//runSubcommand runs the command in args, writing its output to out, and returns the exit code
+func runSubcommand(args []string, out io.Writer) int {
+	err := dispatchSubcommand(args, out)
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, cliUsage)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func dispatchSubcommand(args []string, out io.Writer) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	command := args[0]
+	if len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+		command += " " + args[1]
+		args = args[1:]
+	}
+	switch command {
+	case "groups list":
+		return groupsListCommand(args[1:], out)
+	case "bot list":
+		return botListCommand(args[1:], out)
+	case "bot add":
+		return botAddCommand(args[1:], out)
+	case "bot remove":
+		return botRemoveCommand(args[1:], out)
+	case "bot timezone":
+		return botTimeZoneCommand(args[1:], out)
+	case "bot selection":
+		return botSelectionCommand(args[1:], out)
+	case "bot commands":
+		return botCommandsCommand(args[1:], out)
+	case "run":
+		return runCommand(args[1:], out)
+	case "digest":
+		return digestCommand(args[1:], out)
+	case "schedule":
+		return scheduleCommand(args[1:], out)
+	case "authors":
+		return authorsCommand(args[1:], out)
+	case "search":
+		return searchCLICommand(args[1:], out)
+	case "stats":
+		return statsCLICommand(args[1:], out)
+	}
+	fmt.Fprintln(os.Stderr, fmt.Sprintf("Unknown command %q.", command))
+	return errUsage
+}
+
+//subcommandFlags is a flag set that reports bad flags as a usage error instead of exiting
+func subcommandFlags(name string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	format := flags.String("format", "table", "output format, table or json")
+	return flags, format
+}
+
+func parseSubcommandFlags(flags *flag.FlagSet, format *string, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, fmt.Sprintf("Unexpected argument %q.", flags.Arg(0)))
+		return errUsage
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintln(os.Stderr, fmt.Sprintf("Unknown format %q.", *format))
+		return errUsage
+	}
+	return nil
+}
+
+func writeJSON(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+//groupListing is one line of groups list and bot list
+type groupListing struct {
+	GroupID  string `json:"group_id"`
+	Name     string `json:"name"`
+	Members  int    `json:"members"`
+	BotID    string `json:"bot_id,omitempty"`
+	TimeZone string `json:"time_zone,omitempty"`
+	Error    string `json:"error,omitempty"` //why the group couldn't be looked up, like the user having left it
+}
+
+func groupsListCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("groups list")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	groups, err := getAllGroups()
+	if err != nil {
+		return err
+	}
+	listings := []groupListing{}
+	for _, group := range groups {
+		listings = append(listings, groupListing{GroupID: group.GroupID, Name: group.Name, Members: group.getNumMembers(), BotID: store.GetBotForGroup(group.GroupID)})
+	}
+	return printGroupListings(out, listings, *format)
+}
+
+func botListCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("bot list")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	items, err := store.GetAllItems()
+	if err != nil {
+		return err
+	}
+	listings := []groupListing{}
+	for _, item := range items {
+		listing := groupListing{GroupID: item.GroupId, BotID: item.BotId}
+		listing.TimeZone = groupSettings(item.GroupId).Location
+		group, err := groupMe.getGroup(item.GroupId)
+		if err != nil {
+			listing.Error = err.Error()
+			listings = append(listings, listing)
+			continue
+		}
+		listing.Name = group.Name
+		listing.Members = group.getNumMembers()
+		listings = append(listings, listing)
+	}
+	return printGroupListings(out, listings, *format)
+}
+
+func printGroupListings(out io.Writer, listings []groupListing, format string) error {
+	if format == "json" {
+		return writeJSON(out, listings)
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "GROUP ID\tNAME\tMEMBERS\tBOT ID\tTIME ZONE")
+	for _, listing := range listings {
+		name := listing.Name
+		if listing.Error != "" {
+			name = fmt.Sprintf("(error: %s)", listing.Error)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\n", listing.GroupID, name, listing.Members, listing.BotID, listing.TimeZone)
+	}
+	return writer.Flush()
+}
+
+func botAddCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("bot add")
+	groupFlag := flags.String("group", "", "id or name of the group")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	group, err := findGroup(*groupFlag)
+	if err != nil {
+		return err
+	}
+	botID, err := addBotToGroup(group.GroupID)
+	if err != nil {
+		return err
+	}
+	return printGroupListings(out, []groupListing{{GroupID: group.GroupID, Name: group.Name, Members: group.getNumMembers(), BotID: botID}}, *format)
+}
+
+func botRemoveCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("bot remove")
+	groupFlag := flags.String("group", "", "id or name of the group")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	group, err := findGroup(*groupFlag)
+	if err != nil {
+		return err
+	}
+	err = removeBotFromGroup(group.GroupID)
+	if err != nil {
+		return err
+	}
+	return printGroupListings(out, []groupListing{{GroupID: group.GroupID, Name: group.Name, Members: group.getNumMembers()}}, *format)
+}
+
+func botTimeZoneCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("bot timezone")
+	groupFlag := flags.String("group", "", "id or name of the group")
+	zoneFlag := flags.String("zone", "", "IANA time zone like America/Los_Angeles, or - to go back to the configured location")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	if *zoneFlag == "" {
+		fmt.Fprintln(os.Stderr, "--zone is required.")
+		return errUsage
+	}
+	group, err := findGroup(*groupFlag)
+	if err != nil {
+		return err
+	}
+	zone := *zoneFlag
+	if zone == "-" {
+		zone = ""
+	}
+	err = setTimeZone(group.GroupID, zone)
+	if err != nil {
+		return err
+	}
+	settings := groupSettings(group.GroupID)
+	return printGroupListings(out, []groupListing{{GroupID: group.GroupID, Name: group.Name, Members: group.getNumMembers(), BotID: store.GetBotForGroup(group.GroupID), TimeZone: settings.Location}}, *format)
+}
+
+func botSelectionCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("bot selection")
+	groupFlag := flags.String("group", "", "id or name of the group")
+	strategyFlag := flags.String("strategy", "", "lottery, top, years or authors")
+	topNFlag := flags.Int("top-n", 0, fmt.Sprintf("how many of the most liked the top strategy picks between, default %d", defaultTopN))
+	seedFlag := flags.Int64("seed", 0, "seed for the same pick every time, 0 for random")
+	countFlag := flags.Int("count", 0, "how many memories to post each run, default 1")
+	fallbackFlag := flags.String("fallback", "", "when nothing qualifies: none, nearest, top or note")
+	penaltyFlag := flags.Float64("author-penalty", 0, "0-1, how much less likely an author is for each recent repost of theirs")
+	penaltyDaysFlag := flags.Int("author-penalty-days", 0, fmt.Sprintf("how many days a repost counts against its author, default %d", defaultAuthorPenaltyDays))
+	cooldownFlag := flags.Int("author-cooldown-days", 0, "days after a repost before its author can be picked again, unless nobody else can be")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	if *strategyFlag == "" {
+		fmt.Fprintln(os.Stderr, "--strategy is required.")
+		return errUsage
+	}
+	group, err := findGroup(*groupFlag)
+	if err != nil {
+		return err
+	}
+	rules := &dbConnection.SelectionRules{
+		Strategy:           *strategyFlag,
+		TopN:               *topNFlag,
+		Seed:               *seedFlag,
+		Count:              *countFlag,
+		Fallback:           *fallbackFlag,
+		AuthorPenalty:      *penaltyFlag,
+		AuthorPenaltyDays:  *penaltyDaysFlag,
+		AuthorCooldownDays: *cooldownFlag,
+	}
+	err = setSelectionRules(group.GroupID, rules)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		return writeJSON(out, map[string]interface{}{"group_id": group.GroupID, "name": group.Name, "selection_rules": rules})
+	}
+	fmt.Fprintln(out, fmt.Sprintf("%s now picks by %s", group.Name, describeSelectionRules(rules)))
+	return nil
+}
+
+//commandListing is one chat command and whether a group has it on
+type commandListing struct {
+	Name    string `json:"name"`
+	Help    string `json:"help"`
+	Enabled bool   `json:"enabled"`
+}
+
+func botCommandsCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("bot commands")
+	groupFlag := flags.String("group", "", "id or name of the group")
+	enableFlag := flags.String("enable", "", "comma separated commands to turn on")
+	disableFlag := flags.String("disable", "", "comma separated commands to turn off")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	group, err := findGroup(*groupFlag)
+	if err != nil {
+		return err
+	}
+	for _, change := range []struct {
+		names   string
+		enabled bool
+	}{{*enableFlag, true}, {*disableFlag, false}} {
+		for _, name := range strings.Split(change.names, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if err = setCommandEnabled(group.GroupID, name, change.enabled); err != nil {
+				return err
+			}
+		}
+	}
+	listings := []commandListing{}
+	for _, name := range commandNames() {
+		command := commands[name]
+		listings = append(listings, commandListing{Name: name, Help: describeCommand(command), Enabled: commandEnabled(group.GroupID, command)})
+	}
+	if *format == "json" {
+		return writeJSON(out, listings)
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "COMMAND\tENABLED\tHELP")
+	for _, listing := range listings {
+		fmt.Fprintf(writer, "%s\t%t\t%s\n", listing.Name, listing.Enabled, listing.Help)
+	}
+	return writer.Flush()
+}
+
+func authorsCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("authors")
+	groupFlag := flags.String("group", "", "id or name of the group")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	group, err := findGroup(*groupFlag)
+	if err != nil {
+		return err
+	}
+	report := authorReport(group)
+	if *format == "json" {
+		return writeJSON(out, report)
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "AUTHOR\tREPOSTS\tLIKES\tLAST REPOSTED")
+	for _, author := range report {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%s\n", author.Author, author.Reposts, author.Likes, author.LastReposted)
+	}
+	return writer.Flush()
+}
+
+//searchListing is one search match
+type searchListing struct {
+	MessageID string  `json:"message_id"`
+	Name      string  `json:"name"`
+	Date      string  `json:"date"`
+	Likes     int     `json:"likes"`
+	Score     float64 `json:"score"`
+	Text      string  `json:"text"`
+}
+
+func searchCLICommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("search")
+	groupFlag := flags.String("group", "", "id or name of the group, only the id with --offline")
+	queryFlag := flags.String("query", "", "words to search for, and from:, year:, month:, date: or type: filters")
+	limitFlag := flags.Int("limit", 10, "how many matches to show, 0 for all")
+	offlineFlag := flags.Bool("offline", false, "search the archive as it is without asking GroupMe for anything")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	if len(parseSearchQuery(tokenize(*queryFlag))) == 0 {
+		fmt.Fprintln(os.Stderr, "--query is required.")
+		return errUsage
+	}
+	groupID := *groupFlag
+	if !*offlineFlag {
+		group, err := findGroup(*groupFlag)
+		if err != nil {
+			return err
+		}
+		groupID = group.GroupID
+		err = archive.sync(groupID)
+		if err != nil {
+			return err
+		}
+	} else if groupID == "" {
+		fmt.Fprintln(os.Stderr, "--group is required.")
+		return errUsage
+	}
+	results, err := searchArchive(groupID, tokenize(*queryFlag), *limitFlag)
+	if err != nil {
+		return err
+	}
+	loc := groupSettings(groupID).Loc()
+	listings := []searchListing{}
+	for _, result := range results {
+		listings = append(listings, searchListing{
+			MessageID: result.Message.MessageID,
+			Name:      result.Message.Name,
+			Date:      time.Unix(result.Message.TimeSent, 0).In(loc).Format("2006-01-02 15:04"),
+			Likes:     result.Message.numLikes(),
+			Score:     result.Score,
+			Text:      result.Message.Text,
+		})
+	}
+	if *format == "json" {
+		return writeJSON(out, listings)
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "SCORE\tDATE\tNAME\tLIKES\tTEXT")
+	for _, listing := range listings {
+		fmt.Fprintf(writer, "%.2f\t%s\t%s\t%d\t%s\n", listing.Score, listing.Date, listing.Name, listing.Likes, truncate(listing.Text, 60))
+	}
+	return writer.Flush()
+}
+
+func statsCLICommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("stats")
+	groupFlag := flags.String("group", "", "id or name of the group, only the id with --offline")
+	fromFlag := flags.String("from", "", "first date to count, default the start of the archive")
+	toFlag := flags.String("to", "", "last date to count, default today")
+	offlineFlag := flags.Bool("offline", false, "use the archive as it is without asking GroupMe for anything")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	groupID := *groupFlag
+	names := map[string]string{}
+	if !*offlineFlag {
+		group, err := findGroup(*groupFlag)
+		if err != nil {
+			return err
+		}
+		groupID = group.GroupID
+		names = memberNames(group)
+		err = archive.sync(groupID)
+		if err != nil {
+			return err
+		}
+	} else if groupID == "" {
+		fmt.Fprintln(os.Stderr, "--group is required.")
+		return errUsage
+	}
+	from, to, err := parseStatsRange(*fromFlag, *toFlag, groupSettings(groupID).Loc())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return errUsage
+	}
+	stats, err := statsForGroup(groupID, from, to, names)
+	if err != nil {
+		return err
+	}
+	if *format == "json" {
+		return writeJSON(out, stats)
+	}
+	fmt.Fprintln(out, fmt.Sprintf("%d messages and %d likes, %s", stats.Messages, stats.Likes, stats.describeRange()))
+	fmt.Fprintln(out)
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "MEMBER\tMESSAGES\tLIKES RECEIVED\tLIKES GIVEN\tTOP MESSAGE")
+	for _, member := range stats.Members {
+		top := ""
+		if member.TopMessage != nil {
+			top = fmt.Sprintf("%s ❤️x%d %s", member.TopMessage.Date, member.TopMessage.Likes, truncate(member.TopMessage.Text, 50))
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%s\n", member.Name, member.Messages, member.LikesReceived, member.LikesGiven, top)
+	}
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "HOUR\tMESSAGES\tLIKES")
+	for hour := range stats.LikesByHour {
+		fmt.Fprintf(writer, "%02d:00\t%d\t%d\n", hour, stats.MessagesByHour[hour], stats.LikesByHour[hour])
+	}
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "DAY\tMESSAGES\tLIKES")
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		fmt.Fprintf(writer, "%s\t%d\t%d\n", day, stats.MessagesByWeekday[day.String()], stats.LikesByWeekday[day.String()])
+	}
+	return writer.Flush()
+}
+
+//findGroup finds the group whose id or name (ignoring case) is idOrName
+func findGroup(idOrName string) (Group, error) {
+	if idOrName == "" {
+		fmt.Fprintln(os.Stderr, "--group is required.")
+		return Group{}, errUsage
+	}
+	groups, err := getAllGroups()
+	if err != nil {
+		return Group{}, err
+	}
+	for _, group := range groups {
+		if group.GroupID == idOrName {
+			return group, nil
+		}
+	}
+	var matches []Group
+	for _, group := range groups {
+		if strings.EqualFold(group.Name, idOrName) {
+			matches = append(matches, group)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return Group{}, fmt.Errorf("%d groups are named %q, use the group id", len(matches), idOrName)
+	}
+	return Group{}, fmt.Errorf("no group with the id or name %q", idOrName)
+}
+
+const maxRunDays = 366
+
+func runCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("run")
+	groupFlag := flags.String("group", "", "id or name of the only group to run")
+	dateFlag := flags.String("date", "", "run as though today were this date, YYYY-MM-DD")
+	fromFlag := flags.String("from", "", "first date of a range to run, YYYY-MM-DD")
+	toFlag := flags.String("to", "", "last date of a range to run, YYYY-MM-DD. Defaults to --from")
+	dryRunFlag := flags.Bool("dry-run", false, "show the candidates without posting or saving anything")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	dryRun = dryRun || *dryRunFlag
+	days := []runTime{runningAt(time.Now())}
+	if *dateFlag != "" && *fromFlag != "" {
+		fmt.Fprintln(os.Stderr, "Use either --date or --from, not both.")
+		return errUsage
+	}
+	if *toFlag != "" && *fromFlag == "" {
+		fmt.Fprintln(os.Stderr, "--to needs --from.")
+		return errUsage
+	}
+	if *dateFlag != "" {
+		date, err := parseDate("--date", *dateFlag)
+		if err != nil {
+			return err
+		}
+		days = []runTime{runningOn(date.Date())}
+	} else if *fromFlag != "" {
+		if *toFlag == "" {
+			*toFlag = *fromFlag
+		}
+		var err error
+		days, err = parseDateRange(*fromFlag, *toFlag)
+		if err != nil {
+			return err
+		}
+	}
+	items, err := itemsForGroupFlag(*groupFlag)
+	if err != nil {
+		return err
+	}
+	report := sendMessagesForItems(items, days)
+	err = printRunReport(out, report, *format)
+	if err != nil {
+		return err
+	}
+	return report.err()
+}
+
+func digestCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("digest")
+	groupFlag := flags.String("group", "", "id or name of the only group to post a digest in")
+	fromFlag := flags.String("from", "", "first date in the digest, YYYY-MM-DD. Defaults to today")
+	daysFlag := flags.Int("days", digestDays, "number of days in the digest")
+	dryRunFlag := flags.Bool("dry-run", false, "show the digest without posting or saving anything")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	dryRun = dryRun || *dryRunFlag
+	if *daysFlag < 1 || *daysFlag > maxRunDays {
+		fmt.Fprintln(os.Stderr, fmt.Sprintf("--days must be from 1 to %d.", maxRunDays))
+		return errUsage
+	}
+	days := runningFromToday(time.Now(), *daysFlag)
+	if *fromFlag != "" {
+		from, err := parseDate("--from", *fromFlag)
+		if err != nil {
+			return err
+		}
+		days = runningFromTo(from, from.AddDate(0, 0, *daysFlag-1))
+	}
+	items, err := itemsForGroupFlag(*groupFlag)
+	if err != nil {
+		return err
+	}
+	report := sendDigestsForItems(items, days)
+	err = printRunReport(out, report, *format)
+	if err != nil {
+		return err
+	}
+	return report.err()
+}
+
+func parseDate(name, value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, fmt.Sprintf("Bad %s %q, use YYYY-MM-DD.", name, value))
+		return date, errUsage
+	}
+	return date, nil
+}
+
+//parseDateRange is every date from from to to, both included
+func parseDateRange(fromValue, toValue string) ([]runTime, error) {
+	from, err := parseDate("--from", fromValue)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseDate("--to", toValue)
+	if err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		fmt.Fprintln(os.Stderr, "--to is before --from.")
+		return nil, errUsage
+	}
+	if to.Sub(from) >= maxRunDays*24*time.Hour {
+		fmt.Fprintln(os.Stderr, fmt.Sprintf("A range can be at most %d days.", maxRunDays))
+		return nil, errUsage
+	}
+	return runningFromTo(from, to), nil
+}
+
+//itemsForGroupFlag is every item in the store, or only the named group's if there is a name
+func itemsForGroupFlag(idOrName string) ([]dbConnection.Item, error) {
+	if idOrName == "" {
+		return store.GetAllItems()
+	}
+	group, err := findGroup(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	item, ok, err := store.GetItem(group.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || item.BotId == "" {
+		return nil, fmt.Errorf("group %s: %w", group.Name, errNoBotInGroup)
+	}
+	return []dbConnection.Item{item}, nil
+}
+
+//jobListing is one line of schedule
+type jobListing struct {
+	Name    string `json:"name"`
+	GroupID string `json:"group_id,omitempty"`
+	Window  string `json:"window"`
+	At      string `json:"at"`
+}
+
+func scheduleCommand(args []string, out io.Writer) error {
+	flags, format := subcommandFlags("schedule")
+	seedFlag := flags.Int64("seed", appConfig.Schedule.Seed, "seed for picking times in the windows, 0 for a new one")
+	dryRunFlag := flags.Bool("dry-run", false, "show the next runs without scheduling them")
+	if err := parseSubcommandFlags(flags, format, args); err != nil {
+		return err
+	}
+	randomizer = scheduler.NewRandomizer(*seedFlag)
+	items, err := store.GetAllItems()
+	if err != nil {
+		return err
+	}
+	jobs := nextJobs(items, time.Now())
+	listings := []jobListing{}
+	for _, job := range jobs {
+		window := appConfig.Bot.ScheduleWindow()
+		if job.GroupID != "" {
+			window = groupSettings(job.GroupID).ScheduleWindow()
+		}
+		listings = append(listings, jobListing{Name: job.Name, GroupID: job.GroupID, Window: window.String(), At: job.At.Format(time.RFC3339)})
+	}
+	if *format == "json" {
+		err := writeJSON(out, listings)
+		if err != nil {
+			return err
+		}
+	} else {
+		writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "JOB\tGROUP ID\tWINDOW\tNEXT RUN")
+		for _, listing := range listings {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", listing.Name, listing.GroupID, listing.Window, listing.At)
+		}
+		writer.Flush()
+	}
+	if *dryRunFlag || dryRun {
+		return nil
+	}
+	if appConfig.Schedule.Backend == "cron" {
+		return errors.New("the cron scheduler only runs while the bot is running, start it with no command instead")
+	}
+	return scheduleJobs(jobs)
+}
+
+func printRunReport(out io.Writer, report runReport, format string) error {
+	if format == "json" {
+		if report.Groups == nil {
+			report.Groups = []groupReport{}
+		}
+		return writeJSON(out, report)
+	}
+	if dryRun {
+		printCandidates(out, report.candidates(), format)
+		for _, group := range report.Groups {
+			if group.preview != "" {
+				fmt.Fprintf(out, "\n%s would get:\n%s\n\n", group.Group, group.preview)
+			}
+		}
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "GROUP\tCANDIDATES\tPOSTED\tSECONDS\tERROR")
+	for _, group := range report.Groups {
+		name := group.Group
+		if name == "" {
+			name = group.GroupID
+		}
+		errorMessage := ""
+		if group.Err != nil {
+			errorMessage = group.Err.Error()
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%.1f\t%s\n", name, group.Candidates, strings.Join(group.Posted, ", "), group.Duration.Seconds(), errorMessage)
+	}
+	return writer.Flush()
+}
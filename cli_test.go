@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBotListKeepsGoingPastAMissingGroup(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b")
+	fake.addGroup("g2", "Other", "a")
+	botID, _ := fake.createBot("g2", "MemsBot", "", "")
+	store.AddBot("g2", botID)
+	store.AddBot("gone", "bot-gone") //a group the user has left
+
+	var out bytes.Buffer
+	if code := runSubcommand([]string{"bot", "list", "--format", "json"}, &out); code != 0 {
+		t.Fatalf("bot list exited %d: %s", code, out.String())
+	}
+	var listings []groupListing
+	if err := json.Unmarshal(out.Bytes(), &listings); err != nil {
+		t.Fatal(err)
+	}
+	if len(listings) != 3 {
+		t.Fatalf("listed %+v, want all 3 groups", listings)
+	}
+	for _, listing := range listings {
+		if (listing.Error != "") != (listing.GroupID == "gone") {
+			t.Errorf("listing %+v", listing)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"GroupMeChatBot/dbConnection"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const commandPrefix = "!"
+
+//Command is something the bot does when a message starts with the prefix and its name
+type Command struct {
+	Name  string
+	Usage string //the arguments it takes, like "<date>", for !help
+	Help  string //one line on what it does
+	//Handler does the command and returns the reply, or "" for none. Handlers only go through groupMe and store,
+	//so with the fake client and the memory store they can be run without GroupMe
+	Handler func(message Message, args []string) (string, error)
+	//AlwaysOn commands can't be disabled in a group
+	AlwaysOn bool
+}
+
+var commands = map[string]Command{}
+
+var errNoSuchCommand = errors.New("no such command")
+
+//notArchivedReply is what commands that read the archive say before the group's first run has synced it.
+//Commands never sync it themselves, since a first sync pages through the whole history and would outlast the callback
+const notArchivedReply = "This group's history hasn't been downloaded yet. It will be after the next daily memory."
+
+//registerCommand adds a command to the registry. Commands register themselves from init in their own file
+func registerCommand(command Command) {
+	if _, ok := commands[command.Name]; ok {
+		panic(fmt.Sprintf("command %s registered twice", command.Name))
+	}
+	commands[command.Name] = command
+}
+
+func init() {
+	registerCommand(Command{Name: "help", Usage: "[command]", Help: "lists the commands, or explains one", Handler: helpCommand, AlwaysOn: true})
+}
+
+//routeCommand runs the command in message, if it has one, and replies with its result through the group's bot
+func routeCommand(message Message) {
+	if !isCommand(message) { //most messages are just chatter, so don't read the store for them
+		return
+	}
+	item, ok, err := store.GetItem(message.GroupID)
+	if err != nil {
+		log.Print(fmt.Sprintf("Error reached when getting group %s's settings, ignoring the message.", message.GroupID))
+		log.Print(err)
+		return
+	}
+	if !ok || item.BotId == "" {
+		log.Print(fmt.Sprintf("No bot found for group %s, ignoring the message.", message.GroupID))
+		return
+	}
+	text, err := dispatchCommand(message)
+	if errors.Is(err, errNoSuchCommand) {
+		log.Print(err)
+		return
+	}
+	if err != nil {
+		log.Print(fmt.Sprintf("Error reached when running a command in group %s.", message.GroupID))
+		log.Print(err)
+		text = "Something went wrong, try again later."
+	}
+	if text == "" {
+		return
+	}
+	err = groupMe.postBotMessage(commandBotID(item), text, nil)
+	if err != nil {
+		log.Print(fmt.Sprintf("Error reached when replying in group %s.", message.GroupID))
+		log.Print(err)
+	}
+}
+
+//commandBotID is the bot commands reply with, which is the test group's when running locally
+func commandBotID(item dbConnection.Item) string {
+	if local && testGroupBotID != "" {
+		return testGroupBotID
+	}
+	return item.BotId
+}
+
+//dispatchCommand runs the command in message and returns its reply, without posting it.
+//Messages that aren't commands, and anything from a bot (including this one) or from GroupMe itself, get no reply
+func dispatchCommand(message Message) (string, error) {
+	if !isCommand(message) {
+		return "", nil
+	}
+	name, args, _ := parseCommand(message.Text)
+	command, ok := commands[name]
+	if !ok {
+		return "", fmt.Errorf("%w %s", errNoSuchCommand, name)
+	}
+	if !commandEnabled(message.GroupID, command) {
+		log.Print(fmt.Sprintf("Command %s is disabled in group %s.", command.Name, message.GroupID))
+		return "", nil
+	}
+	log.Print(fmt.Sprintf("Running command %s for group %s.", command.Name, message.GroupID))
+	return command.Handler(message, args)
+}
+
+//isCommand is whether message is a command someone sent, as opposed to chatter, a bot (including this one) or GroupMe itself
+func isCommand(message Message) bool {
+	if message.SenderType == "bot" || message.System { //never answer ourselves or GroupMe's own notices
+		return false
+	}
+	_, _, ok := parseCommand(message.Text)
+	return ok
+}
+
+//parseCommand splits "!name arg "quoted arg"" into its lowercased name and arguments. ok is false if text isn't a command
+func parseCommand(text string) (string, []string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, commandPrefix) {
+		return "", nil, false
+	}
+	fields := tokenize(strings.TrimPrefix(text, commandPrefix))
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	return strings.ToLower(fields[0]), fields[1:], true
+}
+
+//tokenize splits text on spaces, keeping anything in straight or curly double quotes together.
+//A quote that's never closed runs to the end of the text
+func tokenize(text string) []string {
+	var tokens []string
+	var token strings.Builder
+	inQuotes := false
+	hasToken := false
+	for _, r := range text {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			inQuotes = !inQuotes
+			hasToken = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if hasToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				hasToken = false
+			}
+		default:
+			token.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if hasToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
+
+//commandEnabled is false if the group has turned the command off
+func commandEnabled(groupID string, command Command) bool {
+	if command.AlwaysOn {
+		return true
+	}
+	item, _, err := store.GetItem(groupID)
+	if err != nil {
+		log.Print(err)
+	}
+	for _, disabled := range item.DisabledCommands {
+		if disabled == command.Name {
+			return false
+		}
+	}
+	return true
+}
+
+//setCommandEnabled turns a command on or off in a group
+func setCommandEnabled(groupID, name string, enabled bool) error {
+	command, ok := commands[strings.ToLower(strings.TrimPrefix(name, commandPrefix))]
+	if !ok {
+		return fmt.Errorf("%w %s", errNoSuchCommand, name)
+	}
+	if command.AlwaysOn && !enabled {
+		return fmt.Errorf("%s can't be disabled", command.Name)
+	}
+	item, ok, err := store.GetItem(groupID)
+	if err != nil {
+		return err
+	}
+	if !ok || item.BotId == "" {
+		return errNoBotInGroup
+	}
+	var disabledCommands []string
+	for _, disabled := range item.DisabledCommands {
+		if disabled != command.Name {
+			disabledCommands = append(disabledCommands, disabled)
+		}
+	}
+	if !enabled {
+		disabledCommands = append(disabledCommands, command.Name)
+	}
+	item.DisabledCommands = disabledCommands
+	return store.SaveItem(item)
+}
+
+//commandNames is every registered command, sorted
+func commandNames() []string {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func describeCommand(command Command) string {
+	usage := commandPrefix + command.Name
+	if command.Usage != "" {
+		usage += " " + command.Usage
+	}
+	return fmt.Sprintf("%s - %s", usage, command.Help)
+}
+
+func helpCommand(message Message, args []string) (string, error) {
+	if len(args) > 0 {
+		command, ok := commands[strings.ToLower(strings.TrimPrefix(args[0], commandPrefix))]
+		if !ok || !commandEnabled(message.GroupID, command) {
+			return fmt.Sprintf("There's no %s%s command. Try %shelp", commandPrefix, args[0], commandPrefix), nil
+		}
+		return describeCommand(command), nil
+	}
+	lines := []string{"Commands:"}
+	for _, name := range commandNames() {
+		if command := commands[name]; commandEnabled(message.GroupID, command) {
+			lines = append(lines, describeCommand(command))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
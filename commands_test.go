@@ -0,0 +1,86 @@
+package main
+
+import (
+	"GroupMeChatBot/dbConnection"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDispatchCommand(t *testing.T) {
+	newTestGroup(t, "g1", "a", "b")
+
+	reply, err := dispatchCommand(Message{GroupID: "g1", Text: "!HELP context"})
+	if err != nil || reply != describeCommand(commands["context"]) {
+		t.Errorf("!HELP context got %q, %v", reply, err)
+	}
+	reply, err = dispatchCommand(Message{GroupID: "g1", Text: "!context"})
+	if err != nil || reply != "No mems have been posted in this group yet." {
+		t.Errorf("!context got %q, %v", reply, err)
+	}
+	_, err = dispatchCommand(Message{GroupID: "g1", Text: "!nope"})
+	if !errors.Is(err, errNoSuchCommand) {
+		t.Errorf("!nope got %v, want errNoSuchCommand", err)
+	}
+	for _, message := range []Message{
+		{GroupID: "g1", Text: "hello"},
+		{GroupID: "g1", Text: "!help", SenderType: "bot"},
+		{GroupID: "g1", Text: "!help", System: true},
+	} {
+		if reply, err := dispatchCommand(message); reply != "" || err != nil {
+			t.Errorf("%+v got %q, %v, want no reply", message, reply, err)
+		}
+	}
+}
+
+func TestDisabledCommandsAreIgnored(t *testing.T) {
+	newTestGroup(t, "g1", "a", "b")
+	err := setCommandEnabled("g1", "context", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setCommandEnabled("g1", "help", false) == nil {
+		t.Error("help was disabled")
+	}
+	reply, err := dispatchCommand(Message{GroupID: "g1", Text: "!context"})
+	if reply != "" || err != nil {
+		t.Errorf("disabled !context got %q, %v", reply, err)
+	}
+	reply, _ = dispatchCommand(Message{GroupID: "g1", Text: "!help"})
+	if strings.Contains(reply, "!context") || !strings.Contains(reply, "!help") {
+		t.Errorf("!help listed %q", reply)
+	}
+}
+
+//countingStore is a memory store that counts its item reads
+type countingStore struct {
+	*dbConnection.MemoryStore
+	gets *int
+}
+
+func (store countingStore) GetItem(groupId string) (dbConnection.Item, bool, error) {
+	*store.gets++
+	return store.MemoryStore.GetItem(groupId)
+}
+
+func TestRouteCommandOnlyReadsTheStoreForCommands(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b")
+	gets := 0
+	store = countingStore{MemoryStore: store.(*dbConnection.MemoryStore), gets: &gets}
+
+	for _, message := range []Message{
+		{GroupID: "g1", Text: "just chatting"},
+		{GroupID: "g1", Text: "!help", SenderType: "bot"},
+		{GroupID: "g1", Text: "!help", System: true},
+	} {
+		routeCommand(message)
+	}
+	if gets != 0 || len(fake.postedMessages()) != 0 {
+		t.Errorf("non-commands read the store %d times and posted %d messages", gets, len(fake.postedMessages()))
+	}
+
+	routeCommand(Message{GroupID: "g1", Text: "!help"})
+	if gets == 0 || len(fake.postedMessages()) != 1 {
+		t.Errorf("!help read the store %d times and posted %d messages", gets, len(fake.postedMessages()))
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"GroupMeChatBot/scheduler"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+//Config is everything about the bot that used to be a constant in main.go
+type Config struct {
+	APIBaseURL        string                 `yaml:"api_base_url"`
+	CallbackURL       string                 `yaml:"callback_url"`
+	TestGroupName     string                 `yaml:"test_group_name"`
+	ArchivePath       string                 `yaml:"archive_path"`        //a directory for the file backend, a key prefix for s3
+	ArchiveBackend    string                 `yaml:"archive_backend"`     //file or s3
+	ArchiveBucket     string                 `yaml:"archive_bucket"`      //for s3, in the store region
+	Parallelism       int                    `yaml:"parallelism"`         //groups processed at once
+	RequestsPerSecond float64                `yaml:"requests_per_second"` //across every group, 0 for no limit
+	Store             StoreConfig            `yaml:"store"`
+	Schedule          ScheduleConfig         `yaml:"schedule"`
+	Bot               GroupConfig            `yaml:"bot"`
+	Groups            map[string]GroupConfig `yaml:"groups"` //overrides of Bot, keyed by group id
+}
+
+//StoreConfig picks where bot items are kept
+type StoreConfig struct {
+	Backend      string `yaml:"backend"` //dynamo, bolt or memory
+	Table        string `yaml:"table"`
+	RepostsTable string `yaml:"reposts_table"`
+	Region       string `yaml:"region"`
+	Path         string `yaml:"path"`
+}
+
+//ScheduleConfig picks how the next run gets scheduled after each one
+type ScheduleConfig struct {
+	Backend   string `yaml:"backend"`    //cloudwatch, cron or none
+	RuleName  string `yaml:"rule_name"`  //the one CloudWatch rule used when there's no target arn
+	TargetARN string `yaml:"target_arn"` //the Lambda each group's own CloudWatch rule invokes
+	Seed      int64  `yaml:"seed"`       //for picking times in windows, 0 for a new seed every run
+}
+
+//GroupConfig is what can differ between groups. Empty fields fall back to the bot wide value
+type GroupConfig struct {
+	Name           string `yaml:"name"`
+	AvatarURL      string `yaml:"avatar_url"`
+	Location       string `yaml:"location"`
+	Window         string `yaml:"window"`          //when in the day memories get posted, like 08:00-18:00 in Location
+	MemoryCooldown string `yaml:"memory_cooldown"` //how long the group waits between !memory commands, like 1h
+}
+
+func Default() Config {
+	return Config{
+		APIBaseURL:        "https://api.groupme.com/v3",
+		CallbackURL:       "https://7cygninyze.execute-api.us-east-2.amazonaws.com/default/callback",
+		TestGroupName:     "Test Group",
+		ArchivePath:       "archive",
+		ArchiveBackend:    "file",
+		Parallelism:       4,
+		RequestsPerSecond: 5,
+		Store: StoreConfig{
+			Backend:      "dynamo",
+			Table:        "GroupMeBot",
+			RepostsTable: "GroupMeBotReposts",
+			Region:       "us-east-1",
+			Path:         "GroupMeBot.db",
+		},
+		Schedule: ScheduleConfig{
+			Backend:  "cloudwatch",
+			RuleName: "DailyTrigger",
+		},
+		Bot: GroupConfig{
+			Name:           "MemsBot",
+			AvatarURL:      "https://i.groupme.com/1024x1024.png.415633b4d1264b85859f977673e8438c",
+			Location:       "EST",
+			Window:         "08:00-18:00",
+			MemoryCooldown: "1h",
+		},
+	}
+}
+
+//Load reads the YAML file at path on top of the defaults, then applies any env overrides and validates the result.
+//A missing file is fine when required is false, so Lambda can run off env variables alone.
+func Load(path string, required bool) (Config, error) {
+	config := Default()
+	body, err := ioutil.ReadFile(path)
+	if err != nil && (required || !os.IsNotExist(err)) {
+		return config, err
+	}
+	if err == nil {
+		err = yaml.UnmarshalStrict(body, &config)
+		if err != nil {
+			return config, fmt.Errorf("error parsing %s: %v", path, err)
+		}
+	}
+	config.applyEnv()
+	return config, config.Validate()
+}
+
+func (config *Config) applyEnv() {
+	overrides := map[string]*string{
+		"API_BASE_URL":        &config.APIBaseURL,
+		"CALLBACK_URL":        &config.CallbackURL,
+		"TEST_GROUP_NAME":     &config.TestGroupName,
+		"ARCHIVE_PATH":        &config.ArchivePath,
+		"ARCHIVE_BACKEND":     &config.ArchiveBackend,
+		"ARCHIVE_BUCKET":      &config.ArchiveBucket,
+		"STORE_BACKEND":       &config.Store.Backend,
+		"DYNAMO_TABLE":        &config.Store.Table,
+		"REPOSTS_TABLE":       &config.Store.RepostsTable,
+		"DYNAMO_REGION":       &config.Store.Region,
+		"STORE_PATH":          &config.Store.Path,
+		"BOT_NAME":            &config.Bot.Name,
+		"BOT_AVATAR_URL":      &config.Bot.AvatarURL,
+		"BOT_LOCATION":        &config.Bot.Location,
+		"BOT_WINDOW":          &config.Bot.Window,
+		"BOT_MEMORY_COOLDOWN": &config.Bot.MemoryCooldown,
+		"SCHEDULE_BACKEND":    &config.Schedule.Backend,
+		"SCHEDULE_RULE_NAME":  &config.Schedule.RuleName,
+		"SCHEDULE_TARGET_ARN": &config.Schedule.TargetARN,
+	}
+	for key, field := range overrides {
+		if value := os.Getenv(key); value != "" {
+			*field = value
+		}
+	}
+	if value, err := strconv.Atoi(os.Getenv("PARALLELISM")); err == nil {
+		config.Parallelism = value
+	}
+	if value, err := strconv.ParseFloat(os.Getenv("REQUESTS_PER_SECOND"), 64); err == nil {
+		config.RequestsPerSecond = value
+	}
+	if value, err := strconv.ParseInt(os.Getenv("SCHEDULE_SEED"), 10, 64); err == nil {
+		config.Schedule.Seed = value
+	}
+}
+
+func (config Config) Validate() error {
+	for name, value := range map[string]string{"api_base_url": config.APIBaseURL, "callback_url": config.CallbackURL} {
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("%s %q is not a valid url", name, value)
+		}
+	}
+	switch config.Store.Backend {
+	case "dynamo", "bolt", "memory":
+	default:
+		return fmt.Errorf("unknown store backend %q", config.Store.Backend)
+	}
+	switch config.ArchiveBackend {
+	case "file":
+		if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+			return fmt.Errorf("the file archive backend doesn't last between Lambda runs, set archive_backend to s3 and an archive_bucket")
+		}
+	case "s3":
+		if config.ArchiveBucket == "" {
+			return fmt.Errorf("the s3 archive backend needs an archive_bucket")
+		}
+	default:
+		return fmt.Errorf("unknown archive backend %q", config.ArchiveBackend)
+	}
+	switch config.Schedule.Backend {
+	case "cloudwatch", "cron", "none":
+	default:
+		return fmt.Errorf("unknown schedule backend %q", config.Schedule.Backend)
+	}
+	if config.Parallelism < 1 {
+		return fmt.Errorf("parallelism must be at least 1, not %d", config.Parallelism)
+	}
+	if config.Bot.Name == "" {
+		return fmt.Errorf("bot name can't be empty")
+	}
+	_, err := time.LoadLocation(config.Bot.Location)
+	if err != nil {
+		return fmt.Errorf("bot location %q: %v", config.Bot.Location, err)
+	}
+	_, err = scheduler.ParseWindow(config.Bot.Window, time.UTC)
+	if err != nil {
+		return fmt.Errorf("bot %v", err)
+	}
+	_, err = time.ParseDuration(config.Bot.MemoryCooldown)
+	if err != nil {
+		return fmt.Errorf("bot memory cooldown: %v", err)
+	}
+	for groupID, group := range config.Groups {
+		if group.Location != "" {
+			_, err := time.LoadLocation(group.Location)
+			if err != nil {
+				return fmt.Errorf("location %q for group %s: %v", group.Location, groupID, err)
+			}
+		}
+		if group.Window != "" {
+			_, err := scheduler.ParseWindow(group.Window, time.UTC)
+			if err != nil {
+				return fmt.Errorf("group %s %v", groupID, err)
+			}
+		}
+		if group.MemoryCooldown != "" {
+			_, err := time.ParseDuration(group.MemoryCooldown)
+			if err != nil {
+				return fmt.Errorf("group %s memory cooldown: %v", groupID, err)
+			}
+		}
+	}
+	return nil
+}
+
+//ForGroup returns the bot wide settings with any overrides for groupID applied
+func (config Config) ForGroup(groupID string) GroupConfig {
+	groupConfig := config.Bot
+	override := config.Groups[groupID]
+	if override.Name != "" {
+		groupConfig.Name = override.Name
+	}
+	if override.AvatarURL != "" {
+		groupConfig.AvatarURL = override.AvatarURL
+	}
+	if override.Location != "" {
+		groupConfig.Location = override.Location
+	}
+	if override.Window != "" {
+		groupConfig.Window = override.Window
+	}
+	if override.MemoryCooldown != "" {
+		groupConfig.MemoryCooldown = override.MemoryCooldown
+	}
+	return groupConfig
+}
+
+//Loc is the group's location, which Validate has already checked loads
+func (groupConfig GroupConfig) Loc() *time.Location {
+	loc, err := time.LoadLocation(groupConfig.Location)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+//Cooldown is how long the group waits between !memory commands, which Validate has already checked parses
+func (groupConfig GroupConfig) Cooldown() time.Duration {
+	cooldown, err := time.ParseDuration(groupConfig.MemoryCooldown)
+	if err != nil {
+		return 0
+	}
+	return cooldown
+}
+
+//ScheduleWindow is the group's posting window in its location, which Validate has already checked parses
+func (groupConfig GroupConfig) ScheduleWindow() scheduler.Window {
+	window, err := scheduler.ParseWindow(groupConfig.Window, groupConfig.Loc())
+	if err != nil {
+		return scheduler.Window{Start: 0, End: 24 * 60, Location: groupConfig.Loc()}
+	}
+	return window
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const lastMemsContextHeader = "Last Mem's Context:\n- "
+const numContextMessages = 3
+
+func init() {
+	registerCommand(Command{Name: "context", Help: "shows the messages around the last mem", Handler: lastMemsContextCommand})
+}
+
+func getMessagesAround(groupID, messageID string, numMessages int) ([]Message, error) {
+	var messages []Message
+	before, err := groupMe.getMessageBatch(groupID, messageID, "", numMessages)
+	if err != nil {
+		return nil, err
+	}
+	afterID, numAfter := messageID, numMessages
+	if len(before) > 0 {
+		afterID, numAfter = before[0].MessageID, numMessages+1 //starting right before the message means the message itself gets returned too
+	}
+	after, err := groupMe.getMessageBatch(groupID, "", afterID, numAfter)
+	if err != nil {
+		return nil, err
+	}
+	if len(before) == 0 { //nothing to start before the message from, so it's fetched as the one before whatever follows it
+		nextID := ""
+		if len(after) > 0 {
+			nextID = after[0].MessageID
+		}
+		message, err := groupMe.getMessageBatch(groupID, nextID, "", 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(message) == 1 && message[0].MessageID == messageID {
+			before = message
+		}
+	}
+	for _, message := range before {
+		messages = append(messages, *message)
+	}
+	for _, message := range after {
+		messages = append(messages, *message)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].TimeSent < messages[j].TimeSent
+	})
+	return messages, nil
+}
+
+func formatLastMemsContext(messages []Message) string {
+	var lines []string
+	for _, message := range messages {
+		text := message.Text
+		for _, attachment := range message.Attachments {
+			text = strings.TrimSpace(fmt.Sprintf("%s [%s]", text, attachment.Type))
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", message.Name, text))
+	}
+	return lastMemsContextHeader + strings.Join(lines, "\n- ")
+}
+
+func lastMemsContextCommand(message Message, args []string) (string, error) {
+	lastMessageID := store.GetLastMessageIdForGroup(message.GroupID)
+	if lastMessageID == "" {
+		return "No mems have been posted in this group yet.", nil
+	}
+	contextMessages, err := getMessagesAround(message.GroupID, lastMessageID, numContextMessages)
+	if err != nil {
+		return "", err
+	}
+	if len(contextMessages) == 0 {
+		return "Couldn't find the last mem's context.", nil
+	}
+	return formatLastMemsContext(contextMessages), nil
+}
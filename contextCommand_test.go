@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextIncludesTheMem(t *testing.T) {
+	start := time.Date(2019, 7, 4, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name  string
+		mem   int //index of the mem among the messages, oldest first
+		lines []string
+	}{
+		{"first message", 0, []string{"a: 0", "a: 1", "a: 2", "a: 3"}},
+		{"in the middle", 4, []string{"a: 1", "a: 2", "a: 3", "a: 4", "a: 5", "a: 6", "a: 7"}},
+		{"last message", 8, []string{"a: 5", "a: 6", "a: 7", "a: 8"}},
+	} {
+		fake := newTestGroup(t, "g1", "a")
+		var ids []string
+		for i := 0; i < 9; i++ {
+			ids = append(ids, fake.addMessage("g1", "a", string(rune('0'+i)), start.Add(time.Duration(i)*time.Minute)))
+		}
+		store.UpdateLastMessageId("g1", ids[test.mem])
+
+		reply, err := dispatchCommand(Message{GroupID: "g1", Text: "!context"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := lastMemsContextHeader + strings.Join(test.lines, "\n- "); reply != want {
+			t.Errorf("%s: got %q, want %q", test.name, reply, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package dbConnection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var itemsBucket = []byte("GroupMeBot")
+var repostsBucket = []byte("Reposts") //keyed by group id, reposted at and message id so a group's reposts sit together
+
+//BoltStore keeps items in a local bolt file, for self-hosting without AWS
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	log.Print("Opening bolt db at " + path)
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(itemsBucket)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists(repostsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (store *BoltStore) GetItem(groupId string) (Item, bool, error) {
+	item := Item{}
+	found := false
+	err := store.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(itemsBucket).Get([]byte(groupId))
+		if value == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(value, &item)
+	})
+	if err != nil {
+		return item, false, fmt.Errorf("reading item for group %s: %w", groupId, err)
+	}
+	return item, found, nil
+}
+
+func (store *BoltStore) SaveItem(item Item) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).Put([]byte(item.GroupId), value)
+	})
+	if err != nil {
+		return fmt.Errorf("saving item for group %s: %w", item.GroupId, err)
+	}
+	return nil
+}
+
+func (store *BoltStore) AddBot(groupId string, botId string) error {
+	return store.SaveItem(Item{GroupId: groupId, BotId: botId})
+}
+
+func (store *BoltStore) GetBotForGroup(groupId string) string {
+	item, _, err := store.GetItem(groupId)
+	if err != nil {
+		log.Print(err)
+	}
+	return item.BotId
+}
+
+func (store *BoltStore) GetAllItems() ([]Item, error) {
+	var items []Item
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).ForEach(func(key, value []byte) error {
+			item := Item{}
+			err := json.Unmarshal(value, &item)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading items: %w", err)
+	}
+	return items, nil
+}
+
+func (store *BoltStore) RemoveBot(groupId string) {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).Delete([]byte(groupId))
+	})
+	if err != nil {
+		fmt.Println("Got error deleting item:")
+		fmt.Println(err.Error())
+	}
+}
+
+func (store *BoltStore) GetLastMessageIdForGroup(groupId string) string {
+	item, _, err := store.GetItem(groupId)
+	if err != nil {
+		log.Print(err)
+	}
+	return item.LastMessageId
+}
+
+func (store *BoltStore) UpdateLastMessageId(groupId, lastMessageId string) {
+	item, found, err := store.GetItem(groupId)
+	if !found {
+		if err != nil {
+			log.Print(err)
+		}
+		return
+	}
+	item.LastMessageId = lastMessageId
+	err = store.SaveItem(item)
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+//ClaimMemory reads and writes the item in one transaction, which bolt runs one at a time
+func (store *BoltStore) ClaimMemory(groupId string, at, cooldownStart int64) (bool, error) {
+	claimed := false
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(itemsBucket)
+		value := bucket.Get([]byte(groupId))
+		if value == nil {
+			return nil
+		}
+		item := Item{}
+		err := json.Unmarshal(value, &item)
+		if err != nil {
+			return err
+		}
+		if item.LastMemoryAt > cooldownStart {
+			return nil
+		}
+		item.LastMemoryAt = at
+		value, err = json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		claimed = true
+		return bucket.Put([]byte(groupId), value)
+	})
+	if err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
+
+func (store *BoltStore) AddRepost(repost Repost) error {
+	value, err := json.Marshal(repost)
+	if err != nil {
+		return err
+	}
+	key := []byte(fmt.Sprintf("%s/%020d/%s", repost.GroupId, repost.RepostedAt, repost.MessageId))
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(repostsBucket).Put(key, value)
+	})
+	if err != nil {
+		return fmt.Errorf("saving repost of %s in group %s: %w", repost.MessageId, repost.GroupId, err)
+	}
+	return nil
+}
+
+func (store *BoltStore) GetReposts(groupId string) []Repost {
+	var reposts []Repost
+	prefix := []byte(groupId + "/")
+	err := store.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(repostsBucket).Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			repost := Repost{}
+			err := json.Unmarshal(value, &repost)
+			if err != nil {
+				return err
+			}
+			reposts = append(reposts, repost)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println("Got error reading reposts:")
+		fmt.Println(err.Error())
+		return nil
+	}
+	return reposts
+}
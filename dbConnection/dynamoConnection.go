@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -14,96 +15,114 @@ import (
 )
 
 type Item struct {
-	GroupId       string `json:"group_id"`
-	BotId         string `json:"bot_id"`
-	LastMessageId string `json:"last_message_id"`
+	GroupId          string           `json:"group_id"`
+	BotId            string           `json:"bot_id"`
+	LastMessageId    string           `json:"last_message_id"`
+	PopularityRules  *PopularityRules `json:"popularity_rules,omitempty"`
+	TimeZone         string           `json:"time_zone,omitempty"` //IANA name, overriding the configured location
+	SelectionRules   *SelectionRules  `json:"selection_rules,omitempty"`
+	DisabledCommands []string         `json:"disabled_commands,omitempty"` //chat commands turned off in the group, without the prefix
+	LastMemoryAt     int64            `json:"last_memory_at,omitempty"`    //when !memory last posted, for its cooldown
+}
+
+//SelectionRules pick how a group's memory is chosen from its popular messages
+type SelectionRules struct {
+	Strategy string `json:"strategy"` //lottery, top, years or authors
+	TopN     int    `json:"top_n"`    //how many of the most liked the top strategy picks between
+	Seed     int64  `json:"seed"`     //makes every pick the same for the same candidates, 0 for random
+	Count    int    `json:"count"`    //memories posted each run, 1 if not set
+	Fallback string `json:"fallback"` //when nothing qualifies: none, nearest (day), top (all-time) or note
+
+	AuthorPenalty      float64 `json:"author_penalty"`       //0-1, taken off an author's chance for each repost of theirs in the penalty window
+	AuthorPenaltyDays  int     `json:"author_penalty_days"`  //defaults to 30
+	AuthorCooldownDays int     `json:"author_cooldown_days"` //authors reposted this recently aren't picked unless nobody else can be
+}
+
+//PopularityRules replace the default member count tiers for a group. Zero values are ignored
+type PopularityRules struct {
+	MinLikes          int      `json:"min_likes"`
+	MinLikePercentage float32  `json:"min_like_percentage"` //0-100, of the members at the time
+	LikePercentile    float32  `json:"like_percentile"`     //0-100, of the group's own like counts
+	ExcludedPhrases   []string `json:"excluded_phrases"`
 }
 
 type ItemInfo struct {
 	LastMessageId string `json:":l"`
 }
 
+type MemoryClaim struct {
+	At            int64 `json:":a"`
+	CooldownStart int64 `json:":c"`
+}
+
 type ItemKey struct {
 	GroupId string `json:"group_id"`
 }
 
-var dynamoClient *dynamodb.DynamoDB
-
-const tableName = "GroupMeBot"
-
-func startSession() {
-	log.Print("Dynamo session started.")
-	session, err := session.NewSession(&aws.Config{
-		Region: aws.String("us-east-1")},
-	)
-	if err != nil {
-		log.Print("Error reached when starting dynamo session")
-		log.Print(err)
-		panic(err)
-	}
-	dynamoClient = dynamodb.New(session)
+//DynamoStore keeps items in a DynamoDB table
+type DynamoStore struct {
+	dynamoClient     *dynamodb.DynamoDB
+	sessionOnce      sync.Once
+	tableName        string
+	repostsTableName string //keyed by group_id and repost_id
+	region           string
+}
 
+func NewDynamoStore(tableName, repostsTableName, region string) *DynamoStore {
+	return &DynamoStore{tableName: tableName, repostsTableName: repostsTableName, region: region}
 }
 
-func AddBot(groupId string, botId string) {
-	if dynamoClient == nil {
-		startSession() //should i shut it down manually? Optional, but recommended. Probably doesn't matter if using lambda?
-	}
+//startSession only connects the first time it's called, so groups being processed at once share one client
+func (store *DynamoStore) startSession() {
+	store.sessionOnce.Do(func() {
+		log.Print("Dynamo session started.")
+		session, err := session.NewSession(&aws.Config{
+			Region: aws.String(store.region)},
+		)
+		if err != nil {
+			log.Print("Error reached when starting dynamo session")
+			log.Print(err)
+			panic(err)
+		}
+		store.dynamoClient = dynamodb.New(session)
+	})
+}
 
-	item := Item{
+func (store *DynamoStore) AddBot(groupId string, botId string) error {
+	return store.SaveItem(Item{
 		GroupId: groupId,
 		BotId:   botId,
-	}
+	})
+}
+
+func (store *DynamoStore) SaveItem(item Item) error {
+	store.startSession() //should i shut it down manually? Optional, but recommended. Probably doesn't matter if using lambda?
 
 	attributes, err := dynamodbattribute.MarshalMap(item)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	input := &dynamodb.PutItemInput{
 		Item:      attributes,
-		TableName: aws.String(tableName),
-	}
-
-	_, err = dynamoClient.PutItem(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case dynamodb.ErrCodeConditionalCheckFailedException:
-				fmt.Println(dynamodb.ErrCodeConditionalCheckFailedException, aerr.Error())
-			case dynamodb.ErrCodeProvisionedThroughputExceededException:
-				fmt.Println(dynamodb.ErrCodeProvisionedThroughputExceededException, aerr.Error())
-			case dynamodb.ErrCodeResourceNotFoundException:
-				fmt.Println(dynamodb.ErrCodeResourceNotFoundException, aerr.Error())
-			case dynamodb.ErrCodeItemCollectionSizeLimitExceededException:
-				fmt.Println(dynamodb.ErrCodeItemCollectionSizeLimitExceededException, aerr.Error())
-			case dynamodb.ErrCodeTransactionConflictException:
-				fmt.Println(dynamodb.ErrCodeTransactionConflictException, aerr.Error())
-			case dynamodb.ErrCodeRequestLimitExceeded:
-				fmt.Println(dynamodb.ErrCodeRequestLimitExceeded, aerr.Error())
-			case dynamodb.ErrCodeInternalServerError:
-				fmt.Println(dynamodb.ErrCodeInternalServerError, aerr.Error())
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			fmt.Println(err.Error())
-		}
-		return
+		TableName: aws.String(store.tableName),
 	}
+
+	_, err = store.dynamoClient.PutItem(input)
+	if err != nil {
+		return fmt.Errorf("saving item for group %s: %w", item.GroupId, err)
+	}
+	return nil
 }
 
-func GetBotForGroup(groupId string) string {
+func (store *DynamoStore) GetBotForGroup(groupId string) string {
 	fmt.Println("Getting bot_id for group " + groupId)
-	item := Item{}
-	result := GetAllItems()
-	for _, i := range result.Items {
-		err := dynamodbattribute.UnmarshalMap(i, &item)
-		if err != nil {
-			fmt.Println("Got error unmarshalling:")
-			fmt.Println(err.Error())
-			os.Exit(1)
-		}
+	items, err := store.GetAllItems()
+	if err != nil {
+		log.Print(err)
+		return ""
+	}
+	for _, item := range items {
 		if item.GroupId == groupId {
 			return item.BotId
 		}
@@ -112,46 +131,48 @@ func GetBotForGroup(groupId string) string {
 
 }
 
-func GetAllItems() *dynamodb.ScanOutput {
-	if dynamoClient == nil {
-		startSession() //should i shut it down manually?
-	}
+//GetAllItems scans the whole table, a page at a time
+func (store *DynamoStore) GetAllItems() ([]Item, error) {
+	store.startSession() //should i shut it down manually?
 	log.Print("Getting all items from db.")
-	proj := expression.NamesList(expression.Name("group_id"), expression.Name("bot_id"))
-	expr, _ := expression.NewBuilder().WithProjection(proj).Build()
 	params := &dynamodb.ScanInput{
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		FilterExpression:          expr.Filter(),
-		ProjectionExpression:      expr.Projection(),
-		TableName:                 aws.String(tableName),
+		TableName: aws.String(store.tableName),
+	}
+	var items []Item
+	var unmarshalErr error
+	err := store.dynamoClient.ScanPages(params, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, i := range page.Items {
+			item := Item{}
+			unmarshalErr = dynamodbattribute.UnmarshalMap(i, &item)
+			if unmarshalErr != nil {
+				return false
+			}
+			items = append(items, item)
+		}
+		return true
+	})
+	if err == nil {
+		err = unmarshalErr
 	}
-	// Make the DynamoDB Query API call
-	result, err := dynamoClient.Scan(params)
 	if err != nil {
-		log.Print("Error reached when querying db. Exiting.")
-		log.Print(err)
-		//os.Exit(1)
+		return nil, fmt.Errorf("getting all items: %w", err)
 	}
-
 	log.Print("Got all items from db.")
-	return result
+	return items, nil
 }
 
-func RemoveBot(groupId string) {
-	if dynamoClient == nil {
-		startSession() //should i shut it down manually?
-	}
+func (store *DynamoStore) RemoveBot(groupId string) {
+	store.startSession() //should i shut it down manually?
 	input := &dynamodb.DeleteItemInput{
 		Key: map[string]*dynamodb.AttributeValue{
 			"group_id": {
 				S: aws.String(groupId),
 			},
 		},
-		TableName: aws.String(tableName),
+		TableName: aws.String(store.tableName),
 	}
 
-	_, err := dynamoClient.DeleteItem(input)
+	_, err := store.dynamoClient.DeleteItem(input)
 
 	if err != nil {
 		fmt.Println("Got error calling DeleteItem")
@@ -160,10 +181,42 @@ func RemoveBot(groupId string) {
 	}
 }
 
-func UpdateLastMessageId(groupId, lastMessageId string) {
-	if dynamoClient == nil {
-		startSession() //should i shut it down manually?
+func (store *DynamoStore) GetItem(groupId string) (Item, bool, error) {
+	store.startSession()
+	log.Print("Getting item for group " + groupId)
+	item := Item{}
+	key, err := dynamodbattribute.MarshalMap(ItemKey{GroupId: groupId})
+	if err != nil {
+		return item, false, err
+	}
+	input := &dynamodb.GetItemInput{
+		Key:       key,
+		TableName: aws.String(store.tableName),
+	}
+	result, err := store.dynamoClient.GetItem(input)
+	if err != nil {
+		return item, false, fmt.Errorf("getting item for group %s: %w", groupId, err)
+	}
+	if result.Item == nil {
+		return item, false, nil
+	}
+	err = dynamodbattribute.UnmarshalMap(result.Item, &item)
+	if err != nil {
+		return item, false, fmt.Errorf("reading item for group %s: %w", groupId, err)
+	}
+	return item, true, nil
+}
+
+func (store *DynamoStore) GetLastMessageIdForGroup(groupId string) string {
+	item, _, err := store.GetItem(groupId)
+	if err != nil {
+		log.Print(err)
 	}
+	return item.LastMessageId
+}
+
+func (store *DynamoStore) UpdateLastMessageId(groupId, lastMessageId string) {
+	store.startSession() //should i shut it down manually?
 	info := ItemInfo{
 		LastMessageId: lastMessageId,
 	}
@@ -188,12 +241,12 @@ func UpdateLastMessageId(groupId, lastMessageId string) {
 
 	input := &dynamodb.UpdateItemInput{
 		Key:                       key,
-		TableName:                 aws.String(tableName),
+		TableName:                 aws.String(store.tableName),
 		UpdateExpression:          aws.String("set last_message_id = :l"),
 		ExpressionAttributeValues: update,
 	}
 
-	_, err = dynamoClient.UpdateItem(input)
+	_, err = store.dynamoClient.UpdateItem(input)
 	if err != nil {
 		fmt.Println(err.Error())
 		return
@@ -201,3 +254,87 @@ func UpdateLastMessageId(groupId, lastMessageId string) {
 	log.Println("Updated last message id completed!")
 
 }
+
+//ClaimMemory is a conditional update of last_memory_at alone, so it can't undo other changes to the item and two
+// !memory commands at once can't both get through
+func (store *DynamoStore) ClaimMemory(groupId string, at, cooldownStart int64) (bool, error) {
+	store.startSession()
+	key, err := dynamodbattribute.MarshalMap(ItemKey{GroupId: groupId})
+	if err != nil {
+		return false, err
+	}
+	values, err := dynamodbattribute.MarshalMap(MemoryClaim{At: at, CooldownStart: cooldownStart})
+	if err != nil {
+		return false, err
+	}
+	input := &dynamodb.UpdateItemInput{
+		Key:                       key,
+		TableName:                 aws.String(store.tableName),
+		UpdateExpression:          aws.String("set last_memory_at = :a"),
+		ConditionExpression:       aws.String("attribute_exists(group_id) AND (attribute_not_exists(last_memory_at) OR last_memory_at <= :c)"),
+		ExpressionAttributeValues: values,
+	}
+	_, err = store.dynamoClient.UpdateItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (store *DynamoStore) AddRepost(repost Repost) error {
+	store.startSession()
+	repost.RepostId = repost.Key()
+	attributes, err := dynamodbattribute.MarshalMap(repost)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      attributes,
+		TableName: aws.String(store.repostsTableName),
+	}
+	_, err = store.dynamoClient.PutItem(input)
+	if err != nil {
+		return fmt.Errorf("saving repost of %s in group %s: %w", repost.MessageId, repost.GroupId, err)
+	}
+	return nil
+}
+
+func (store *DynamoStore) GetReposts(groupId string) []Repost {
+	store.startSession()
+	log.Print("Getting reposts for group " + groupId)
+	keyCondition := expression.Key("group_id").Equal(expression.Value(groupId))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCondition).Build()
+	if err != nil {
+		fmt.Println("Got error building repost query:")
+		fmt.Println(err.Error())
+		return nil
+	}
+	input := &dynamodb.QueryInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		TableName:                 aws.String(store.repostsTableName),
+	}
+	var reposts []Repost
+	err = store.dynamoClient.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, i := range page.Items {
+			repost := Repost{}
+			err := dynamodbattribute.UnmarshalMap(i, &repost)
+			if err != nil {
+				fmt.Println("Got error unmarshalling repost:")
+				fmt.Println(err.Error())
+				continue
+			}
+			reposts = append(reposts, repost)
+		}
+		return true
+	})
+	if err != nil {
+		fmt.Println("Got error querying reposts")
+		fmt.Println(err.Error())
+	}
+	return reposts
+}
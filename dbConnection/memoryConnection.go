@@ -0,0 +1,105 @@
+package dbConnection
+
+import (
+	"sort"
+	"sync"
+)
+
+//MemoryStore keeps items in a map, for tests and throwaway runs
+type MemoryStore struct {
+	mutex   sync.Mutex
+	items   map[string]Item
+	reposts map[string][]Repost
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]Item), reposts: make(map[string][]Repost)}
+}
+
+func (store *MemoryStore) AddBot(groupId string, botId string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.items[groupId] = Item{GroupId: groupId, BotId: botId}
+	return nil
+}
+
+func (store *MemoryStore) GetBotForGroup(groupId string) string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.items[groupId].BotId
+}
+
+func (store *MemoryStore) GetAllItems() ([]Item, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	var items []Item
+	for _, item := range store.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].GroupId < items[j].GroupId
+	})
+	return items, nil
+}
+
+func (store *MemoryStore) GetItem(groupId string) (Item, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	item, ok := store.items[groupId]
+	return item, ok, nil
+}
+
+func (store *MemoryStore) SaveItem(item Item) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.items[item.GroupId] = item
+	return nil
+}
+
+func (store *MemoryStore) RemoveBot(groupId string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.items, groupId)
+}
+
+func (store *MemoryStore) GetLastMessageIdForGroup(groupId string) string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.items[groupId].LastMessageId
+}
+
+func (store *MemoryStore) UpdateLastMessageId(groupId, lastMessageId string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	item, ok := store.items[groupId]
+	if !ok {
+		return
+	}
+	item.LastMessageId = lastMessageId
+	store.items[groupId] = item
+}
+
+func (store *MemoryStore) ClaimMemory(groupId string, at, cooldownStart int64) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	item, ok := store.items[groupId]
+	if !ok || item.LastMemoryAt > cooldownStart {
+		return false, nil
+	}
+	item.LastMemoryAt = at
+	store.items[groupId] = item
+	return true, nil
+}
+
+func (store *MemoryStore) AddRepost(repost Repost) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.reposts[repost.GroupId] = append(store.reposts[repost.GroupId], repost)
+	return nil
+}
+
+func (store *MemoryStore) GetReposts(groupId string) []Repost {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return append([]Repost(nil), store.reposts[groupId]...)
+}
@@ -0,0 +1,64 @@
+package dbConnection
+
+import (
+	"fmt"
+)
+
+//Store is everything the bot keeps about the groups it's in
+type Store interface {
+	AddBot(groupId string, botId string) error
+	GetBotForGroup(groupId string) string
+	GetAllItems() ([]Item, error)
+	GetItem(groupId string) (Item, bool, error)
+	SaveItem(item Item) error
+	RemoveBot(groupId string)
+	GetLastMessageIdForGroup(groupId string) string
+	UpdateLastMessageId(groupId, lastMessageId string)
+	//ClaimMemory sets only the item's last_memory_at to at, and only if it's unset or no later than cooldownStart.
+	//It's false when another !memory has claimed the cooldown since then
+	ClaimMemory(groupId string, at, cooldownStart int64) (bool, error)
+	AddRepost(repost Repost) error
+	GetReposts(groupId string) []Repost
+}
+
+//Repost is a record of the bot reposting one of a group's messages
+type Repost struct {
+	GroupId    string `json:"group_id"`
+	MessageId  string `json:"message_id"` //the original message, not the bot's post
+	AuthorName string `json:"author_name"`
+	AuthorId   string `json:"author_id"`
+	SentAt     int64  `json:"sent_at"`
+	RepostedAt int64  `json:"reposted_at"`
+	Likes      int    `json:"likes"`
+	RepostId   string `json:"repost_id"` //reposted_at#message_id, unique even when several reposts share a second
+}
+
+//Key is the repost's RepostId, made from when it was reposted and the message so no two reposts share one
+func (repost Repost) Key() string {
+	if repost.RepostId != "" {
+		return repost.RepostId
+	}
+	return fmt.Sprintf("%d#%s", repost.RepostedAt, repost.MessageId)
+}
+
+//Config picks a Store backend and where it keeps its data
+type Config struct {
+	Backend          string //dynamo, bolt or memory
+	TableName        string
+	RepostsTableName string
+	Region           string
+	Path             string
+}
+
+func NewStore(config Config) (Store, error) {
+	switch config.Backend {
+	case "dynamo":
+		return NewDynamoStore(config.TableName, config.RepostsTableName, config.Region), nil
+	case "bolt":
+		return NewBoltStore(config.Path)
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", config.Backend)
+	}
+}
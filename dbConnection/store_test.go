@@ -0,0 +1,77 @@
+package dbConnection
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bolt.db.Close() })
+	return map[string]Store{"memory": NewMemoryStore(), "bolt": bolt}
+}
+
+func TestClaimMemoryWaitsOutTheCooldown(t *testing.T) {
+	for name, store := range testStores(t) {
+		if claimed, err := store.ClaimMemory("g1", 100, 40); claimed || err != nil {
+			t.Errorf("%s: claimed %t, %v for a group without the bot", name, claimed, err)
+		}
+		err := store.SaveItem(Item{GroupId: "g1", BotId: "b1", LastMessageId: "m1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, claim := range []struct {
+			at, cooldownStart int64
+			claimed           bool
+		}{
+			{100, 40, true},   //never claimed
+			{120, 60, false},  //100 is in the cooldown
+			{160, 100, true},  //the cooldown just ran out
+			{170, 110, false}, //and started again
+		} {
+			claimed, err := store.ClaimMemory("g1", claim.at, claim.cooldownStart)
+			if claimed != claim.claimed || err != nil {
+				t.Errorf("%s: claim at %d got %t, %v, want %t", name, claim.at, claimed, err, claim.claimed)
+			}
+		}
+		item, _, err := store.GetItem("g1")
+		if err != nil || item.LastMemoryAt != 160 || item.BotId != "b1" || item.LastMessageId != "m1" {
+			t.Errorf("%s: item is %+v, %v, want only last_memory_at changed to 160", name, item, err)
+		}
+	}
+}
+
+func TestClaimMemoryOnlyLetsOneThrough(t *testing.T) {
+	for name, store := range testStores(t) {
+		err := store.SaveItem(Item{GroupId: "g1", BotId: "b1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wait sync.WaitGroup
+		var mutex sync.Mutex
+		numClaimed := 0
+		for i := 0; i < 20; i++ {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				claimed, err := store.ClaimMemory("g1", 1000, 0)
+				if err != nil {
+					t.Error(err)
+				}
+				if claimed {
+					mutex.Lock()
+					numClaimed++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wait.Wait()
+		if numClaimed != 1 {
+			t.Errorf("%s: %d of 20 claims at once went through, want 1", name, numClaimed)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"GroupMeChatBot/dbConnection"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const digestDays = 7
+const digestTextLength = 100
+const groupMeMaxMessageLength = 1000
+
+//sendDigestsForItems posts a digest of days in every item's group and logs the report
+func sendDigestsForItems(items []dbConnection.Item, days []runTime) runReport {
+	findTestGroup(items)
+
+	report := runAllGroups(items, appConfig.Parallelism, func(item dbConnection.Item, report *groupReport) error {
+		return sendDigestForGroup(item, days, report)
+	})
+	report.log()
+	return report
+}
+
+//sendDigestForGroup posts one message with a memory from each of days, like a "this week in history".
+//In a dry run the digest goes in the report's preview instead
+func sendDigestForGroup(item dbConnection.Item, days []runTime, report *groupReport) error {
+	group, err := groupMe.getGroup(item.GroupId)
+	if err != nil {
+		return err
+	}
+	report.Group = group.Name
+	loc := groupSettings(group.GroupID).Loc()
+	var dates []time.Time
+	for _, at := range days {
+		dates = append(dates, at(loc))
+	}
+	popularMessagesByDate, err := getPopularMessagesFromDates(group, dates)
+	if err != nil {
+		return err
+	}
+	selection := newSelection(item.SelectionRules, loc, store.GetReposts(group.GroupID))
+	var memories []Message
+	for i, date := range dates {
+		popularMessages := popularMessagesByDate[i]
+		report.Candidates += len(popularMessages)
+		memory := getMessageToPost(selection, &popularMessages)
+		if dryRun {
+			report.candidates = append(report.candidates, reportCandidates(group, selection, date, popularMessages, memory)...)
+		}
+		if memory.numLikes() > 0 {
+			memories = append(memories, memory)
+			selection.featured(memory)
+		}
+	}
+	if len(memories) == 0 {
+		log.Print(fmt.Sprintf("No memories from %s for group %s, not posting a digest.", describeDates(dates), group.Name))
+		return nil
+	}
+	text := formatDigest(dates, memories, loc)
+	if dryRun {
+		report.preview = text
+		return nil
+	}
+	botID := item.BotId
+	if local {
+		botID = testGroupBotID
+	}
+	err = groupMe.postBotMessage(botID, text, nil)
+	if err != nil {
+		return err
+	}
+	for _, memory := range memories {
+		report.Posted = append(report.Posted, memory.MessageID)
+		if !local {
+			err = recordRepost(group.GroupID, memory)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//formatDigest lists one memory a line under a header naming the dates, cut to fit in one GroupMe message
+func formatDigest(dates []time.Time, memories []Message, loc *time.Location) string {
+	header := fmt.Sprintf("Memories from %s:", describeDates(dates))
+	if len(dates) == digestDays {
+		header = fmt.Sprintf("This week in history (%s):", describeDates(dates))
+	}
+	lines := []string{header, ""}
+	for _, memory := range memories {
+		sent := time.Unix(memory.TimeSent, 0).In(loc)
+		text := memory.Text
+		if text == "" && len(memory.Attachments) > 0 {
+			text = attachmentFallback(memory.Attachments[0])
+		}
+		lines = append(lines, fmt.Sprintf("%d/%d/%02d - %s: \"%s\" ❤️x%d", int(sent.Month()), sent.Day(), sent.Year()%100, memory.Name, truncate(text, digestTextLength), memory.numLikes()))
+	}
+	return truncate(strings.Join(lines, "\n"), groupMeMaxMessageLength)
+}
+
+//describeDates is "10/18" for one date or "10/18 - 10/24" for a range
+func describeDates(dates []time.Time) string {
+	first := dates[0]
+	last := dates[len(dates)-1]
+	if len(dates) == 1 {
+		return fmt.Sprintf("%d/%d", int(first.Month()), first.Day())
+	}
+	return fmt.Sprintf("%d/%d - %d/%d", int(first.Month()), first.Day(), int(last.Month()), last.Day())
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestPostsAWeekInOneMessage(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	loc := appConfig.Bot.Loc()
+	fake.addMessage("g1", "Sam", "fireworks", time.Date(2019, 7, 4, 21, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "Alex", "", time.Date(2020, 7, 6, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "Jo", "after the week", time.Date(2019, 7, 11, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "Kim", "not popular", time.Date(2019, 7, 5, 12, 0, 0, 0, loc))
+
+	var out bytes.Buffer
+	if code := runSubcommand([]string{"digest", "--from", "2021-07-04"}, &out); code != 0 {
+		t.Fatalf("digest exited %d: %s", code, out.String())
+	}
+	posted := fake.postedMessages()
+	if len(posted) != 1 {
+		t.Fatalf("posted %+v, want one digest", posted)
+	}
+	want := strings.Join([]string{
+		"This week in history (7/4 - 7/10):",
+		"",
+		"7/4/19 - Sam: \"fireworks\" ❤️x4",
+		"7/6/20 - Alex: \"\" ❤️x4",
+	}, "\n")
+	if posted[0].Text != want {
+		t.Errorf("posted %q, want %q", posted[0].Text, want)
+	}
+	if reposts := store.GetReposts("g1"); len(reposts) != 2 {
+		t.Errorf("recorded %d reposts, want one for each memory", len(reposts))
+	}
+}
+
+func TestDescribeDates(t *testing.T) {
+	fourth := time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC)
+	if described := describeDates([]time.Time{fourth}); described != "7/4" {
+		t.Errorf("one date is %q", described)
+	}
+	if described := describeDates([]time.Time{fourth, fourth.AddDate(0, 0, 1), fourth.AddDate(0, 1, 0)}); described != "7/4 - 8/4" {
+		t.Errorf("a range is %q", described)
+	}
+}
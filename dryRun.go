@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+//candidateReport is one message that could have been posted, as shown by -dry-run
+type candidateReport struct {
+	Group           string  `json:"group"`
+	For             string  `json:"for"` //the date being run
+	MessageID       string  `json:"message_id"`
+	Name            string  `json:"name"`
+	Text            string  `json:"text"`
+	Date            string  `json:"date"`
+	Likes           int     `json:"likes"`
+	Members         int     `json:"members"`
+	PercentageLikes float32 `json:"percentage_likes"`
+	AlreadyReposted bool    `json:"already_reposted"`
+	Probability     float32 `json:"probability"`
+	Chosen          bool    `json:"chosen"`
+}
+
+func reportCandidates(group Group, selection selection, date time.Time, candidates []Message, chosen ...Message) []candidateReport {
+	loc := groupSettings(group.GroupID).Loc()
+	runDate := date.Format("2006-01-02")
+	var reports []candidateReport
+	for _, message := range candidates {
+		reports = append(reports, candidateReport{
+			Group:           group.Name,
+			For:             runDate,
+			MessageID:       message.MessageID,
+			Name:            message.Name,
+			Text:            message.Text,
+			Date:            time.Unix(message.TimeSent, 0).In(loc).Format("2006-01-02 15:04"),
+			Likes:           message.numLikes(),
+			Members:         message.numMembersAtTime,
+			PercentageLikes: message.percentageLikes(),
+			AlreadyReposted: message.alreadyReposted,
+			Probability:     selectionProbability(selection, message, candidates),
+			Chosen:          containsMessage(chosen, message),
+		})
+	}
+	if len(candidates) == 0 {
+		reports = append(reports, candidateReport{Group: group.Name, For: runDate})
+	}
+	return reports
+}
+
+func printCandidates(out io.Writer, reports []candidateReport, format string) {
+	if format == "json" {
+		candidates := []candidateReport{}
+		for _, report := range reports {
+			if report.MessageID != "" {
+				candidates = append(candidates, report)
+			}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(candidates)
+		return
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "GROUP\tFOR\tCHOSEN\tDATE\tNAME\tLIKES\tMEMBERS\t%LIKES\tREPOSTED\tPROBABILITY\tTEXT")
+	for _, report := range reports {
+		if report.MessageID == "" {
+			fmt.Fprintf(writer, "%s\t%s\t\t\t(no candidates)\t\t\t\t\t\t\n", report.Group, report.For)
+			continue
+		}
+		chosen := ""
+		if report.Chosen {
+			chosen = "*"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%.0f%%\t%t\t%.1f%%\t%s\n", report.Group, report.For, chosen, report.Date, report.Name, report.Likes, report.Members, report.PercentageLikes*100, report.AlreadyReposted, report.Probability*100, truncate(report.Text, 60))
+	}
+	writer.Flush()
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-3]) + "..."
+}
+
+func containsMessage(messages []Message, message Message) bool {
+	for _, candidate := range messages {
+		if candidate.MessageID == message.MessageID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const fakeGroupsPerPage = 10
+
+//PostedMessage is a bot post recorded by the fake client
+type PostedMessage struct {
+	BotID       string
+	GroupID     string
+	Text        string
+	Attachments []Attachment
+}
+
+//fakeGroupMe is an in-memory GroupMe for running the bot with no network
+type fakeGroupMe struct {
+	mutex    sync.Mutex
+	groups   []*Group
+	messages map[string][]*Message //newest first, like the API returns them
+	bots     map[string]string     //bot id to group id
+	botNames map[string]string
+	posted   []PostedMessage
+	failures map[string]error //group id to the error every call for that group returns
+	nextID   int
+}
+
+func newFakeGroupMe() *fakeGroupMe {
+	return &fakeGroupMe{
+		messages: make(map[string][]*Message),
+		bots:     make(map[string]string),
+		botNames: make(map[string]string),
+		failures: make(map[string]error),
+		nextID:   1,
+	}
+}
+
+func (fake *fakeGroupMe) newID() string {
+	id := fmt.Sprintf("%d", fake.nextID)
+	fake.nextID++
+	return id
+}
+
+//addGroup adds a group with one member per name
+func (fake *fakeGroupMe) addGroup(groupID, name string, memberNames ...string) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	group := &Group{ID: groupID, GroupID: groupID, Name: name}
+	for _, memberName := range memberNames {
+		group.Members = append(group.Members, map[string]interface{}{"nickname": memberName})
+	}
+	fake.groups = append(fake.groups, group)
+}
+
+//addMessage adds a message to a group, liked by likedBy. It returns the new message id
+func (fake *fakeGroupMe) addMessage(groupID, name, text string, timeSent time.Time, likedBy ...string) string {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	message := &Message{
+		Name:       name,
+		Text:       text,
+		MessageID:  fake.newID(),
+		FavoriteBy: likedBy,
+		TimeSent:   timeSent.Unix(),
+		SenderType: "user",
+		GroupID:    groupID,
+	}
+	fake.insertMessage(message)
+	return message.MessageID
+}
+
+//addAttachments adds attachments to a message already added
+func (fake *fakeGroupMe) addAttachments(groupID, messageID string, attachments ...Attachment) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	for _, message := range fake.messages[groupID] {
+		if message.MessageID == messageID {
+			message.Attachments = append(message.Attachments, attachments...)
+		}
+	}
+}
+
+//addEvent adds a GroupMe system message for event, like someone being added to the group. It returns the new message id
+func (fake *fakeGroupMe) addEvent(groupID, text string, event Event, timeSent time.Time) string {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	message := &Message{
+		Name:       "GroupMe",
+		Text:       text,
+		MessageID:  fake.newID(),
+		TimeSent:   timeSent.Unix(),
+		Event:      event,
+		SenderType: "system",
+		GroupID:    groupID,
+		System:     true,
+	}
+	fake.insertMessage(message)
+	return message.MessageID
+}
+
+func (fake *fakeGroupMe) insertMessage(message *Message) {
+	groupMessages := append(fake.messages[message.GroupID], message)
+	sort.SliceStable(groupMessages, func(i, j int) bool {
+		return groupMessages[i].TimeSent > groupMessages[j].TimeSent
+	})
+	fake.messages[message.GroupID] = groupMessages
+}
+
+//failGroup makes every later call about groupID return err, to check that one bad group doesn't stop a run
+func (fake *fakeGroupMe) failGroup(groupID string, err error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.failures[groupID] = err
+}
+
+//postedMessages returns every bot post made so far
+func (fake *fakeGroupMe) postedMessages() []PostedMessage {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return append([]PostedMessage(nil), fake.posted...)
+}
+
+func (fake *fakeGroupMe) getPageOfGroups(page int) (Groups, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	groups := Groups{}
+	for i := (page - 1) * fakeGroupsPerPage; i < page*fakeGroupsPerPage && i < len(fake.groups); i++ {
+		groups.Groups = append(groups.Groups, *fake.groups[i])
+	}
+	return groups, nil
+}
+
+func (fake *fakeGroupMe) getGroup(groupID string) (Group, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.failures[groupID]; err != nil {
+		return Group{}, err
+	}
+	for _, group := range fake.groups {
+		if group.GroupID == groupID {
+			return *group, nil
+		}
+	}
+	return Group{}, &APIError{Method: "GET", Path: "/groups/" + groupID, StatusCode: 404, Errors: []string{"not found"}}
+}
+
+func (fake *fakeGroupMe) getMessageBatch(groupID, beforeID, afterID string, numMessages int) ([]*Message, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if err := fake.failures[groupID]; err != nil {
+		return nil, err
+	}
+	groupMessages := fake.messages[groupID]
+	index := func(messageID string) int {
+		for i, message := range groupMessages {
+			if message.MessageID == messageID {
+				return i
+			}
+		}
+		return -1
+	}
+	var batch []*Message
+	if afterID != "" { //the API returns the messages right after after_id, oldest first
+		for i := index(afterID) - 1; i >= 0 && len(batch) < numMessages; i-- {
+			batch = append(batch, fake.copyMessage(groupMessages[i]))
+		}
+		return batch, nil
+	}
+	start := 0
+	if beforeID != "" {
+		start = index(beforeID) + 1
+		if start == 0 {
+			return nil, nil
+		}
+	}
+	for i := start; i < len(groupMessages) && len(batch) < numMessages; i++ {
+		batch = append(batch, fake.copyMessage(groupMessages[i]))
+	}
+	return batch, nil
+}
+
+func (fake *fakeGroupMe) copyMessage(message *Message) *Message {
+	messageCopy := *message
+	messageCopy.FavoriteBy = append([]string(nil), message.FavoriteBy...)
+	messageCopy.Attachments = append([]Attachment(nil), message.Attachments...)
+	return &messageCopy
+}
+
+func (fake *fakeGroupMe) postBotMessage(botID, text string, attachments []Attachment) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	groupID, ok := fake.bots[botID]
+	if !ok {
+		return &APIError{Method: "POST", Path: "/bots/post", StatusCode: 404, Errors: []string{"bot not found"}}
+	}
+	if err := fake.failures[groupID]; err != nil {
+		return err
+	}
+	fake.posted = append(fake.posted, PostedMessage{BotID: botID, GroupID: groupID, Text: text, Attachments: attachments})
+	message := &Message{
+		Name:        fake.botNames[botID],
+		Text:        text,
+		MessageID:   fake.newID(),
+		TimeSent:    time.Now().Unix(),
+		SenderType:  "bot",
+		SenderID:    botID,
+		GroupID:     groupID,
+		Attachments: append([]Attachment(nil), attachments...),
+	}
+	fake.insertMessage(message)
+	return nil
+}
+
+func (fake *fakeGroupMe) createBot(groupID, name, avatarURL, callbackURL string) (string, error) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	botID := "bot" + fake.newID()
+	fake.bots[botID] = groupID
+	fake.botNames[botID] = name
+	return botID, nil
+}
+
+func (fake *fakeGroupMe) deleteBot(botID string) error {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	delete(fake.bots, botID)
+	delete(fake.botNames, botID)
+	return nil
+}
@@ -9,4 +9,6 @@ require (
 	github.com/gin-gonic/gin v1.5.0
 	github.com/subosito/gotenv v1.2.0
 	github.com/urfave/cli v1.22.2 // indirect
+	go.etcd.io/bbolt v1.3.5
+	gopkg.in/yaml.v2 v2.2.2
 )
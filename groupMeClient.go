@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+//GroupMeClient is every call the bot makes against the GroupMe API
+type GroupMeClient interface {
+	getPageOfGroups(page int) (Groups, error)
+	getGroup(groupID string) (Group, error)
+	getMessageBatch(groupID, beforeID, afterID string, numMessages int) ([]*Message, error)
+	postBotMessage(botID, text string, attachments []Attachment) error
+	createBot(groupID, name, avatarURL, callbackURL string) (string, error)
+	deleteBot(botID string) error
+}
+
+var groupMe GroupMeClient
+
+//groupMeAPI talks to the real GroupMe API at urlBase
+type groupMeAPI struct {
+	accessToken string
+	urlBase     string
+	http        *httpLayer
+}
+
+func newGroupMeAPI(accessToken, urlBase string, requestsPerSecond float64) *groupMeAPI {
+	return &groupMeAPI{accessToken: accessToken, urlBase: urlBase, http: newHTTPLayer(requestsPerSecond)}
+}
+
+func (api *groupMeAPI) getPageOfGroups(page int) (Groups, error) {
+	log.Print("Getting page of groups.")
+	groups := Groups{}
+	body, err := api.http.do(http.MethodGet, fmt.Sprintf("%s/groups?token=%s&page=%d", api.urlBase, api.accessToken, page), nil)
+	if err != nil {
+		return groups, fmt.Errorf("getting page %d of groups: %w", page, err)
+	}
+	log.Print("Page of groups retrieved.")
+	err = json.Unmarshal(body, &groups)
+	if err != nil {
+		return groups, fmt.Errorf("unmarshaling page %d of groups: %w", page, err)
+	}
+	log.Print(fmt.Sprintf("Got %d groups when getting page of groups.", len(groups.Groups)))
+	return groups, nil
+}
+
+func (api *groupMeAPI) getGroup(groupID string) (Group, error) {
+	url := fmt.Sprintf("%s/groups/%s?token=%s", api.urlBase, groupID, api.accessToken)
+	group := OneGroup{}
+	body, err := api.http.do(http.MethodGet, url, nil)
+	if err != nil {
+		return group.Group, fmt.Errorf("getting group %s: %w", groupID, err)
+	}
+	err = json.Unmarshal(body, &group)
+	if err != nil {
+		return group.Group, fmt.Errorf("unmarshaling group %s: %w", groupID, err)
+	}
+	return group.Group, nil
+}
+
+func (api *groupMeAPI) getMessageBatch(groupID, beforeID, afterID string, numMessages int) ([]*Message, error) {
+	url := fmt.Sprintf("%s/groups/%s/messages?token=%s&limit=%d", api.urlBase, groupID, api.accessToken, numMessages)
+	if beforeID != "" {
+		url += fmt.Sprintf("&before_id=%s", beforeID)
+	}
+	if afterID != "" {
+		url += fmt.Sprintf("&after_id=%s", afterID)
+	}
+	body, err := api.http.do(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting messages for group %s: %w", groupID, err)
+	}
+	if len(body) == 0 { //GroupMe answers 304 with no body once there are no more messages
+		return nil, nil
+	}
+	messageResponse := MessagesResponse{}
+	err = json.Unmarshal(body, &messageResponse)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling messages for group %s: %w", groupID, err)
+	}
+	return messageResponse.MessagesMap.Messages, nil
+}
+
+func (api *groupMeAPI) postBotMessage(botID, text string, attachments []Attachment) error {
+	url := fmt.Sprintf("%s/bots/post", api.urlBase)
+	params := map[string]interface{}{
+		"bot_id": botID,
+		"text":   text,
+	}
+	if len(attachments) > 0 {
+		params["attachments"] = attachments
+	}
+	_, err := api.http.do(http.MethodPost, url, params)
+	if err != nil {
+		return fmt.Errorf("posting message: %w", err)
+	}
+	log.Print("Post message api request completed.")
+	return nil
+}
+
+func (api *groupMeAPI) createBot(groupID, name, avatarURL, callbackURL string) (string, error) {
+	url := fmt.Sprintf("%s/bots?token=%s", api.urlBase, api.accessToken)
+	params := map[string]interface{}{
+		"bot": map[string]interface{}{
+			"name":         name,
+			"group_id":     groupID,
+			"avatar_url":   avatarURL,
+			"callback_url": callbackURL,
+		},
+	}
+	body, err := api.http.do(http.MethodPost, url, params)
+	if err != nil {
+		return "", fmt.Errorf("creating bot in group %s: %w", groupID, err)
+	}
+	bot := BotCreationResponse{}
+	err = json.Unmarshal(body, &bot)
+	if err != nil {
+		return "", fmt.Errorf("unmarshaling new bot for group %s: %w", groupID, err)
+	}
+	return bot.Response.Info.BotID, nil
+
+}
+
+func (api *groupMeAPI) deleteBot(botID string) error {
+	url := fmt.Sprintf("%s/bots/destroy?token=%s", api.urlBase, api.accessToken)
+	params := map[string]interface{}{
+		"bot_id": botID,
+	}
+	_, err := api.http.do(http.MethodPost, url, params)
+	if err != nil {
+		return fmt.Errorf("deleting bot %s: %w", botID, err)
+	}
+	return nil
+}
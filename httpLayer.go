@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const requestTimeout = 30 * time.Second
+const maxRetries = 4
+const baseRetryDelay = 500 * time.Millisecond
+const maxRetryDelay = 30 * time.Second
+
+//APIError is a GroupMe response that wasn't a success, with the errors GroupMe gave in meta.errors
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Errors     []string
+}
+
+func (err *APIError) Error() string {
+	if len(err.Errors) == 0 {
+		return fmt.Sprintf("%s %s: status %d", err.Method, err.Path, err.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: status %d: %s", err.Method, err.Path, err.StatusCode, strings.Join(err.Errors, ", "))
+}
+
+//temporary is true for rate limiting and server errors, which are worth retrying
+func (err *APIError) temporary() bool {
+	return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= 500
+}
+
+//apiMeta struct
+type apiMeta struct {
+	Meta struct {
+		Code   int      `json:"code"`
+		Errors []string `json:"errors"`
+	} `json:"meta"`
+}
+
+//httpLayer makes GroupMe requests with a deadline each, retrying 429s, 5xxs and network errors with exponential backoff.
+//Requests that aren't safe to send twice, like posting a message, are only retried on 429s and on errors from before they were sent
+type httpLayer struct {
+	client     *http.Client
+	limiter    *rateLimiter
+	timeout    time.Duration
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+func newHTTPLayer(requestsPerSecond float64) *httpLayer {
+	return &httpLayer{
+		client:     &http.Client{},
+		limiter:    newRateLimiter(requestsPerSecond),
+		timeout:    requestTimeout,
+		maxRetries: maxRetries,
+		baseDelay:  baseRetryDelay,
+		maxDelay:   maxRetryDelay,
+	}
+}
+
+//rateLimiter spaces requests out evenly, shared by every goroutine making GroupMe calls
+type rateLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+//newRateLimiter allows requestsPerSecond requests a second. 0 or less means no limit
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	limiter := &rateLimiter{}
+	if requestsPerSecond > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return limiter
+}
+
+//wait blocks until the caller's turn to make a request
+func (limiter *rateLimiter) wait() {
+	if limiter.interval == 0 {
+		return
+	}
+	limiter.mutex.Lock()
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	turn := limiter.next
+	limiter.next = limiter.next.Add(limiter.interval)
+	limiter.mutex.Unlock()
+	time.Sleep(turn.Sub(now))
+}
+
+//do sends the request and returns the response body. A 304, which GroupMe sends when there are no more messages, returns no body and no error
+func (layer *httpLayer) do(method, rawURL string, params interface{}) ([]byte, error) {
+	var payload []byte
+	if params != nil {
+		var err error
+		payload, err = json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, sent, err := layer.attempt(method, rawURL, payload)
+		if err == nil {
+			return body, nil
+		}
+		if !retryable(method, err, sent) || attempt >= layer.maxRetries {
+			return nil, err
+		}
+		delay := layer.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if delay > layer.maxDelay { //a huge Retry-After would sleep past the Lambda's deadline
+			delay = layer.maxDelay
+		}
+		log.Print(fmt.Sprintf("Retrying %s %s in %s after error: %s", method, redact(rawURL), delay, err))
+		time.Sleep(delay)
+	}
+}
+
+//retryable is whether a failed request can be sent again. GETs can always be, but anything else may already have
+//done its work on a 5xx or a timeout, so it's only retried when GroupMe rate limited it or it never went out
+func retryable(method string, err error, sent bool) bool {
+	idempotent := method == http.MethodGet || method == http.MethodHead
+	if apiErr, ok := err.(*APIError); ok {
+		return apiErr.temporary() && (idempotent || apiErr.StatusCode == http.StatusTooManyRequests)
+	}
+	return idempotent || !sent
+}
+
+//attempt sends the request once. sent is true once any of the request has been written to the connection
+func (layer *httpLayer) attempt(method, rawURL string, payload []byte) (body []byte, retryAfter time.Duration, sent bool, err error) {
+	layer.limiter.wait()
+	ctx, cancel := context.WithTimeout(context.Background(), layer.timeout)
+	defer cancel()
+	var wrote int32 //set by the transport's goroutines
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteHeaders: func() { atomic.StoreInt32(&wrote, 1) },
+		WroteRequest: func(httptrace.WroteRequestInfo) { atomic.StoreInt32(&wrote, 1) },
+	})
+	var requestBody io.Reader
+	if payload != nil {
+		requestBody = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequest(method, rawURL, requestBody)
+	if err != nil {
+		return nil, 0, false, redactError(err, rawURL)
+	}
+	request = request.WithContext(ctx)
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := layer.client.Do(request)
+	if err != nil {
+		return nil, 0, atomic.LoadInt32(&wrote) == 1, redactError(err, rawURL)
+	}
+	defer resp.Body.Close()
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, true, redactError(err, rawURL)
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, 0, true, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{Method: method, Path: redact(rawURL), StatusCode: resp.StatusCode}
+		meta := apiMeta{}
+		if json.Unmarshal(body, &meta) == nil {
+			apiErr.Errors = meta.Meta.Errors
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, retryAfter, true, apiErr
+	}
+	return body, 0, true, nil
+}
+
+//backoff doubles the delay every attempt, with up to half of it again added as jitter
+func (layer *httpLayer) backoff(attempt int) time.Duration {
+	delay := layer.baseDelay << uint(attempt)
+	if delay > layer.maxDelay || delay <= 0 {
+		delay = layer.maxDelay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+//redactError takes the url, and so the access token, out of errors from net/http, which put the whole url in their text
+func redactError(err error, rawURL string) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		redacted := *urlErr
+		redacted.URL = redact(rawURL)
+		return &redacted
+	}
+	return err
+}
+
+//redact strips the query string so the access token never ends up in logs or errors
+func redact(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "(unparseable url)"
+	}
+	return parsed.Path
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testHTTPLayer() *httpLayer {
+	layer := newHTTPLayer(0)
+	layer.baseDelay = time.Millisecond
+	layer.maxDelay = time.Millisecond
+	return layer
+}
+
+func TestServerErrorsOnlyRetryGets(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	layer := testHTTPLayer()
+
+	_, err := layer.do(http.MethodPost, server.URL+"/bots/post?token=secret", map[string]string{"text": "hi"})
+	if err == nil || requests != 1 {
+		t.Fatalf("POST got %v after %d requests, want an error after 1", err, requests)
+	}
+	requests = 0
+	_, err = layer.do(http.MethodGet, server.URL+"/groups?token=secret", nil)
+	if err == nil || requests != layer.maxRetries+1 {
+		t.Fatalf("GET got %v after %d requests, want an error after %d", err, requests, layer.maxRetries+1)
+	}
+}
+
+func TestRateLimitedPostsAreRetried(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	_, err := testHTTPLayer().do(http.MethodPost, server.URL+"/bots/post?token=secret", map[string]string{"text": "hi"})
+	if err != nil || requests != 2 {
+		t.Fatalf("got %v after %d requests, want success after 2", err, requests)
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	_, err := testHTTPLayer().do(http.MethodGet, server.URL+"/groups?token=secret", nil)
+	if err != nil || requests != 2 {
+		t.Fatalf("got %v after %d requests, want success after 2", err, requests)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("waited %s, want no longer than the max delay", elapsed)
+	}
+}
+
+func TestTransportErrorsHideToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close() //nothing is listening, so every request fails before it's sent
+
+	_, err := testHTTPLayer().do(http.MethodPost, server.URL+"/bots/post?token=secret", nil)
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Fatalf("error leaks the token: %v", err)
+	}
+}
@@ -1,39 +1,35 @@
 package main
 
 import (
-	"GroupMeChatBot/cloudwatchTrigger"
+	"GroupMeChatBot/config"
 	"GroupMeChatBot/dbConnection"
+	"GroupMeChatBot/scheduler"
 	"bufio"
-	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"math/rand"
-	"net/http"
 	"os"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/subosito/gotenv"
 )
 
-const urlBase = "https://api.groupme.com/v3"
-const botName = "MemsBot"
-const aviLink = "https://i.groupme.com/1024x1024.png.415633b4d1264b85859f977673e8438c"
-const location = "EST"
-const callbackURL = "https://7cygninyze.execute-api.us-east-2.amazonaws.com/default/callback"
-
 var local = false
+var dryRun = false
+var dryRunFormat = "table"
 var menu = false
 var testGroupBotID string
+var menuScanner = bufio.NewScanner(os.Stdin)
+var store dbConnection.Store
+var appConfig = config.Default()
 
 //BotInfo struct
 type BotInfo struct {
@@ -74,13 +70,41 @@ type Groups struct {
 
 //Event struct
 type Event struct {
-	Type string `json:"type"`
+	Type string    `json:"type"`
+	Data EventData `json:"data"`
+}
+
+//EventData struct
+type EventData struct {
+	AddedUsers  []EventUser `json:"added_users"`
+	RemovedUser EventUser   `json:"removed_user"`
+	User        EventUser   `json:"user"`
+}
+
+//EventUser struct
+type EventUser struct {
+	ID       json.Number `json:"id"`
+	Nickname string      `json:"nickname"`
 }
 
-//Attachment struct
+//Attachment is anything GroupMe attaches to a message. Which fields are set depends on Type
 type Attachment struct {
-	Type string `json:"type"`
-	URL  string `json:"url"`
+	Type        string     `json:"type"`
+	URL         string     `json:"url,omitempty"`         //image, linked_image and video
+	PreviewURL  string     `json:"preview_url,omitempty"` //video
+	Lat         coordinate `json:"lat,omitempty"`         //location
+	Lng         coordinate `json:"lng,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Placeholder string     `json:"placeholder,omitempty"` //emoji
+	Charmap     [][]int    `json:"charmap,omitempty"`
+	UserIDs     []string   `json:"user_ids,omitempty"` //mentions, with each one's [start, length] in the text
+	Loci        [][]int    `json:"loci,omitempty"`
+	ReplyID     string     `json:"reply_id,omitempty"` //reply
+	BaseReplyID string     `json:"base_reply_id,omitempty"`
+	UserID      string     `json:"user_id,omitempty"`
+	FileID      string     `json:"file_id,omitempty"`  //file
+	PollID      string     `json:"poll_id,omitempty"`  //poll
+	EventID     string     `json:"event_id,omitempty"` //event
 }
 
 //Message struct
@@ -93,8 +117,13 @@ type Message struct {
 	Event       Event        `json:"event"`
 	Attachments []Attachment `json:"attachments"`
 	SenderType  string       `json:"sender_type"`
+	SenderID    string       `json:"sender_id"`
+	UserID      string       `json:"user_id"`
+	GroupID     string       `json:"group_id"`
+	System      bool         `json:"system"`
 
 	numMembersAtTime int
+	alreadyReposted  bool
 }
 
 func (message Message) numLikes() int {
@@ -115,179 +144,90 @@ type MessagesResponse struct {
 	MessagesMap Messages `json:"response"`
 }
 
-func getPageOfGroups(accessToken string, page int) Groups {
-	log.Print("Getting page of groups.")
-	resp, err := http.Get(fmt.Sprintf("%s/groups?token=%s&page=%d", urlBase, accessToken, page))
-	if err != nil {
-		log.Print("Fatal error reached when getting page of groups.")
-		log.Fatalln(err)
-	}
-	defer resp.Body.Close()
-	log.Print("Page of groups retrieved.")
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Print("Fatal error reached when reading page of groups.")
-		log.Fatalln(err)
-	}
-	groups := Groups{}
-	log.Print("Got " + string(len(groups.Groups)) + " groups when getting page of groups.")
-	err = json.Unmarshal(body, &groups)
-	if err != nil {
-		log.Print("Fatal error reached when unmarshaling page of groups.")
-		panic(err)
-	}
-	return groups
-}
-
-func getMessageBatch(groupID string, accessToken string, beforeID string) ([]byte, error) {
-	numMessages := 100
-	url := fmt.Sprintf("%s/groups/%s/messages?token=%s&limit=%d", urlBase, groupID, accessToken, numMessages)
-	if beforeID != "" {
-		url += fmt.Sprintf("&before_id=%s", beforeID)
-	}
-	resp, err := http.Get(url)
-	if err != nil {
-		log.Print("Fatal error reached when getting message batch.")
-		log.Fatalln(err)
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Print("Fatal error reached when reading message batch.")
-		log.Fatalln(err)
-	}
-	return body, err
-
-}
-
-func countUsersAddedOrRemoved(str string) int {
-	count := 1
-	for _, c := range str {
-		if c == ',' {
-			count++
-		}
-	}
-	if count == 1 && strings.Contains(str, " and ") {
-		count++
-	}
-	return count
-}
-
-func addMessagesFromDate(numMembers *int, year int, month time.Month, day int, messages *[]*Message, popularMessagesFromDate *[]Message, popularMessagesFromDateAlreadyReposted *[]Message, repostedAlreadyMap map[string]int) {
+func addMessagesFromDate(groupConfig config.GroupConfig, popularity popularity, timeline membershipTimeline, year int, month time.Month, day int, messages *[]*Message, popularMessagesFromDate *[]Message, popularMessagesFromDateAlreadyReposted *[]Message, repostedYears map[string]int) {
 
+	loc := groupConfig.Loc()
 	for _, message := range *messages {
-		loc, _ := time.LoadLocation(location)
 		messageDate := time.Unix(message.TimeSent, 0).In(loc)
 		messageYear, messageMonth, messageDay := messageDate.Date()
-		if messageYear == year { //messages from this year don't need to be examined
+		if messageYear >= year { //messages from this year or later aren't memories of it
 			continue
 		}
 		if messageMonth != month || messageDay != day { //messages not from this date don't need to be examined
 			continue
 		}
-		if message.Name == "MemsBot" { //identify and extract messages that have already been reposted by memsbot
-			if strings.HasPrefix(message.Text, "Last Mem's Context:\n- ") {
-				continue
-			}
-			re, err := regexp.Compile(` \n\n- .* \| \d{1,2}/\d{1,2}/\d{2} \| ❤️x\d*`)
-
-			if err != nil {
-				log.Print(fmt.Sprintf("error trying to compile regexp"))
-				continue
-			}
-			messageText := message.Text
+		addIfPopular(popularity, timeline, year, message, popularMessagesFromDate, popularMessagesFromDateAlreadyReposted, repostedYears)
+	}
 
-			match := re.FindStringIndex(messageText)
-			if match == nil {
-				continue
-			}
-			attachmentText := ""
-			if len(message.Attachments) > 0 {
-				attachmentText += message.Attachments[0].URL
-			}
-			if match[0]-1 <= 1 {
-				messageText = attachmentText
-			} else {
-				messageText = messageText[1:match[0]-1] + attachmentText
-			}
-			if repostedAlreadyMap[messageText] == 0 {
-				repostedAlreadyMap[messageText] = messageYear //avoids replacing previous entries in the map
-			} else {
-				log.Print(fmt.Sprintf("Not adding this message to the repostedAlready map because it's already in there %s", messageText))
-			}
-		}
-		attachementText := ""
-		if len(message.Attachments) > 0 {
-			attachementText += message.Attachments[0].URL
-		}
-		messageText := message.Text + attachementText
-		alreadyReposted := false
-		if repostedAlreadyMap[messageText] >= year-1 { //add messages already posted by memsbot to a separate list
-			log.Print(fmt.Sprintf("This message isn't a candidate to be reposted because it was reposted last year: %s", messageText))
-			continue
-		} else if repostedAlreadyMap[messageText] > 0 {
-			alreadyReposted = true
-		}
-		if strings.Contains(message.Event.Type, "bot") || message.SenderType == "bot" { //other groupme messages (like those from polls and calendar events) don't get reposted
-			continue
-		}
-		if message.Name == "GroupMe" && strings.Contains(message.Text, "added") {
-			*numMembers = *numMembers - countUsersAddedOrRemoved(message.Text)
-		}
-		if message.Name == "GroupMe" && strings.Contains(message.Text, "removed") {
-			*numMembers = *numMembers + countUsersAddedOrRemoved(message.Text)
-		}
-		message.numMembersAtTime = *numMembers
-		if message.isPopular() {
-			if alreadyReposted {
-				*popularMessagesFromDateAlreadyReposted = append(*popularMessagesFromDateAlreadyReposted, *message)
-			} else {
-				*popularMessagesFromDate = append(*popularMessagesFromDate, *message)
-			}
-			log.Print(fmt.Sprintf("Adding message to popular messages from today. Its time is %d", message.TimeSent))
-			log.Print(fmt.Sprintf("Message's month is %d and its day is %d", messageMonth, messageDay))
-			log.Print(fmt.Sprintf("Message has been reposted before: %t", alreadyReposted))
+}
 
+//addIfPopular adds message to one of the lists if it's popular and wasn't reposted in the last year
+func addIfPopular(popularity popularity, timeline membershipTimeline, year int, message *Message, popularMessages *[]Message, popularMessagesAlreadyReposted *[]Message, repostedYears map[string]int) {
+	alreadyReposted := false
+	if repostedYears[message.MessageID] >= year-1 { //add messages already posted by memsbot to a separate list
+		log.Print(fmt.Sprintf("This message isn't a candidate to be reposted because it was reposted last year: %s", message.Text))
+		return
+	} else if repostedYears[message.MessageID] > 0 {
+		alreadyReposted = true
+	}
+	if strings.Contains(message.Event.Type, "bot") || message.SenderType == "bot" { //other groupme messages (like those from polls and calendar events) don't get reposted
+		return
+	}
+	message.numMembersAtTime = timeline.membersAt(message.TimeSent)
+	message.alreadyReposted = alreadyReposted
+	if message.isPopular(popularity) {
+		if alreadyReposted {
+			*popularMessagesAlreadyReposted = append(*popularMessagesAlreadyReposted, *message)
+		} else {
+			*popularMessages = append(*popularMessages, *message)
 		}
+		log.Print(fmt.Sprintf("Adding message to popular messages. Its time is %d", message.TimeSent))
+		log.Print(fmt.Sprintf("Message has been reposted before: %t", alreadyReposted))
 	}
+}
 
+//candidateFinder finds a group's popular messages from its archive, synced once when it's made
+type candidateFinder struct {
+	group         Group
+	groupConfig   config.GroupConfig
+	repostedYears map[string]int
+	popularity    popularity
+	timeline      membershipTimeline
 }
 
-func getPopularMessagesFromDate(group Group, accessToken string, date time.Time) []Message {
+//newCandidateFinder syncs the group's archive and makes a finder for it
+func newCandidateFinder(group Group) (candidateFinder, error) {
+	err := archive.sync(group.GroupID)
+	if err != nil {
+		return candidateFinder{}, err
+	}
+	return loadCandidateFinder(group)
+}
+
+//loadCandidateFinder makes a finder for the group's archive as it is, for chat commands, which can't wait for a sync
+func loadCandidateFinder(group Group) (candidateFinder, error) {
 	groupID := group.GroupID
-	numMembers := group.getNumMembers()
-	year, month, day := date.Date()
+	groupConfig := groupSettings(groupID)
+	item, _, err := store.GetItem(groupID)
+	if err != nil {
+		return candidateFinder{}, err
+	}
+	return candidateFinder{
+		group:         group,
+		groupConfig:   groupConfig,
+		repostedYears: getRepostedYears(groupID, groupConfig.Loc()),
+		popularity:    newPopularity(groupID, item.PopularityRules),
+		timeline:      buildMembershipTimeline(group.getNumMembers(), archive.allMessages(groupID)),
+	}, nil
+}
 
-	beforeID := ""
-	var allMessages []Message
+//fromDate is the popular messages from date in earlier years, or the ones reposted before if those are all there are
+func (finder candidateFinder) fromDate(date time.Time) []Message {
+	year, month, day := date.Date()
 	var popularMessagesFromDate []Message
 	var popularMessagesFromDateAlreadyReposted []Message
-
-	repostedAlreadyMap := make(map[string]int)
-
-	for {
-		body, err := getMessageBatch(groupID, accessToken, beforeID)
-		if err != nil || len(body) == 0 {
-			break
-		}
-		messageResponse := MessagesResponse{}
-		err = json.Unmarshal(body, &messageResponse)
-		if err != nil {
-			log.Print("Fatal error reached when unmarshalling message batch.")
-			panic(err)
-		}
-		messagesBatch := messageResponse.MessagesMap.Messages
-		addMessagesFromDate(&numMembers, year, month, day, &messagesBatch, &popularMessagesFromDate, &popularMessagesFromDateAlreadyReposted, repostedAlreadyMap)
-		if len(messagesBatch) == 0 {
-			break
-		}
-		lastMessage := messagesBatch[len(messagesBatch)-1]
-		beforeID = lastMessage.MessageID
-
-		for _, message := range messagesBatch {
-			allMessages = append(allMessages, *message)
-		}
-	}
+	messagesFromDate := archive.messagesFromDate(finder.group.GroupID, finder.groupConfig.Loc(), month, day)
+	addMessagesFromDate(finder.groupConfig, finder.popularity, finder.timeline, year, month, day, &messagesFromDate, &popularMessagesFromDate, &popularMessagesFromDateAlreadyReposted, finder.repostedYears)
 
 	if len(popularMessagesFromDate) == 0 {
 		popularMessagesFromDate = popularMessagesFromDateAlreadyReposted
@@ -295,26 +235,56 @@ func getPopularMessagesFromDate(group Group, accessToken string, date time.Time)
 	return popularMessagesFromDate
 }
 
-func (message Message) isPopular() bool {
-	if strings.Contains(strings.ToLower(message.Text), "like this") {
-		return false
+//allTime is the group's count most liked popular messages from any day before date's year
+func (finder candidateFinder) allTime(date time.Time, count int) []Message {
+	popularMessages := finder.matching(date, func(sent time.Time) bool {
+		return sent.Year() < date.Year()
+	})
+	sort.SliceStable(popularMessages, func(i, j int) bool {
+		return popularMessages[i].percentageLikes() > popularMessages[j].percentageLikes()
+	})
+	if len(popularMessages) > count {
+		popularMessages = popularMessages[:count]
+	}
+	return popularMessages
+}
+
+//matching is the popular messages sent when keep says, in the group's location, that weren't reposted in the year before
+//date, or the ones reposted before if those are all there are
+func (finder candidateFinder) matching(date time.Time, keep func(sent time.Time) bool) []Message {
+	var popularMessages []Message
+	var popularMessagesAlreadyReposted []Message
+	loc := finder.groupConfig.Loc()
+	for _, message := range archive.allMessages(finder.group.GroupID) {
+		if !keep(time.Unix(message.TimeSent, 0).In(loc)) {
+			continue
+		}
+		addIfPopular(finder.popularity, finder.timeline, date.Year(), message, &popularMessages, &popularMessagesAlreadyReposted, finder.repostedYears)
 	}
-	if message.numMembersAtTime <= 5 && (message.numLikes() < message.numMembersAtTime-1) {
-		return false
-	} else if message.numMembersAtTime >= 17 && message.numLikes() < 8 {
-		return false
-	} else if message.numMembersAtTime > 5 && message.numMembersAtTime < 17 && message.numLikes() < (4+(message.numMembersAtTime-5)/3) {
-		return false
+	if len(popularMessages) == 0 {
+		popularMessages = popularMessagesAlreadyReposted
 	}
-	return true
+	return popularMessages
+}
 
+//getPopularMessagesFromDates finds the group's popular messages from each of dates in earlier years, syncing the archive only once.
+//The popular messages for dates[i] are at index i
+func getPopularMessagesFromDates(group Group, dates []time.Time) ([][]Message, error) {
+	finder, err := newCandidateFinder(group)
+	if err != nil {
+		return nil, err
+	}
+	popularMessagesByDate := make([][]Message, len(dates))
+	for i, date := range dates {
+		popularMessagesByDate[i] = finder.fromDate(date)
+	}
+	return popularMessagesByDate, nil
 }
 
-func postMessage(message Message, botID string) {
-	loc, _ := time.LoadLocation(location)
+//repostText is message's text in quotes over a byline with its author, date in loc and likes
+func repostText(message Message, loc *time.Location) string {
 	messageDate := time.Unix(message.TimeSent, 0).In(loc)
 	messageYear, messageMonth, messageDay := messageDate.Date()
-	url := fmt.Sprintf("%s/bots/post", urlBase)
 	messageText := fmt.Sprintf("\"%s\"", message.Text)
 	if len(messageText) == 2 {
 		messageText = ""
@@ -323,44 +293,48 @@ func postMessage(message Message, botID string) {
 	if text == "\"\"" {
 		text = ""
 	}
-	params := map[string]interface{}{
-		"bot_id": botID,
-		"text":   text,
+	return text
+}
+
+//postMessage reposts message, under heading if there is one
+func postMessage(message Message, botID string, heading string) error {
+	text := repostText(message, groupSettings(message.GroupID).Loc())
+	offset := 0
+	if message.Text != "" {
+		offset = 1 //the opening quote
 	}
-	if len(message.Attachments) > 0 {
-		params["picture_url"] = message.Attachments[0].URL
+	if heading != "" {
+		text = heading + "\n\n" + text
+		offset += utf8.RuneCountInString(heading + "\n\n")
 	}
-	bytesRepresentation, err := json.Marshal(params)
-
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(bytesRepresentation))
-	if err != nil {
-		log.Print("Fatal error reached when posting message.")
-		log.Fatalln(err)
+	attachments, fallbacks := repostAttachments(message.Attachments, offset)
+	if len(fallbacks) > 0 {
+		text += "\n" + strings.Join(fallbacks, "\n")
 	}
-
-	defer resp.Body.Close()
-	log.Print("Post message api request completed.")
-
+	return groupMe.postBotMessage(botID, text, attachments)
 }
 
-func getMessageToPost(messages *[]Message) Message {
+//getMessageToPost picks one of messages with the group's selection strategy, or returns an empty message if there are none
+func getMessageToPost(selection selection, messages *[]Message) Message {
 	if len(*messages) == 0 {
 		return Message{}
 	}
-	sort.Slice(*messages, func(i, j int) bool {
-		return (*messages)[i].percentageLikes() > (*messages)[j].percentageLikes()
+	sort.SliceStable(*messages, func(i, j int) bool { //a stable order so a seeded selection always picks the same message
+		if (*messages)[i].percentageLikes() != (*messages)[j].percentageLikes() {
+			return (*messages)[i].percentageLikes() > (*messages)[j].percentageLikes()
+		}
+		return (*messages)[i].MessageID < (*messages)[j].MessageID
 	})
 
-	var total float32 = 0.0
-	for _, message := range *messages {
-		total += message.percentageLikes()
+	weights := selection.weights(*messages)
+	var total float64
+	for _, weight := range weights {
+		total += weight
 	}
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-	randNum := rng.Float32() * total
-	for _, message := range *messages {
-		randNum -= message.percentageLikes()
-		if randNum <= 0 {
+	randNum := selection.float64() * total
+	for i, message := range *messages {
+		randNum -= weights[i]
+		if randNum < 0 || (randNum <= 0 && weights[i] > 0) {
 			return message
 		}
 	}
@@ -369,202 +343,429 @@ func getMessageToPost(messages *[]Message) Message {
 
 }
 
-func getAllGroups(accessToken string) []Group {
+//selectionProbability is the chance getMessageToPost picks message out of messages
+func selectionProbability(selection selection, message Message, messages []Message) float32 {
+	weights := selection.weights(messages)
+	var total, weight float64
+	for i, candidate := range messages {
+		total += weights[i]
+		if candidate.MessageID == message.MessageID {
+			weight = weights[i]
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float32(weight / total)
+}
+
+func getAllGroups() ([]Group, error) {
 	var allGroups []Group
 	for i := 1; ; i++ {
-		page := getPageOfGroups(accessToken, i)
+		page, err := groupMe.getPageOfGroups(i)
+		if err != nil {
+			return allGroups, err
+		}
 		if len(page.Groups) == 0 {
 			break
 		}
 		allGroups = append(allGroups, page.Groups...)
 	}
-	return allGroups
+	return allGroups, nil
 }
 
-func createBot(groupID, accessToken string) string {
-	url := fmt.Sprintf("%s/bots?token=%s", urlBase, accessToken)
-	params := map[string]interface{}{
-		"bot": map[string]interface{}{
-			"name":         botName,
-			"group_id":     groupID,
-			"avatar_url":   aviLink,
-			"callback_url": callbackURL,
-		},
+func handler(event scheduledRun) error {
+	if menu {
+		log.Print("Getting groups...")
+		groups, err := getAllGroups()
+		if err != nil {
+			return err
+		}
+		log.Print(fmt.Sprintf("Got %d groups.", len(groups)))
+		log.Print(fmt.Sprintf("Accessing the menu"))
+		showMenu(groups)
+		return nil
+	} else if local || dryRun {
+		log.Print(fmt.Sprintf("Local run..."))
+		return sendMessages()
+
 	}
-	bytesRepresentation, err := json.Marshal(params)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(bytesRepresentation))
-	if err != nil {
-		log.Fatalln(err)
+	log.Print(fmt.Sprintf("Sending messages..."))
+	return runScheduledJob(scheduler.Job{GroupID: event.GroupID})
+}
+
+func showMenu(groups []Group) {
+	fmt.Println("Make a selection:")
+	fmt.Println("[1] Add the bot to a group.")
+	fmt.Println("[2] Remove the bot from a group.")
+	fmt.Println("[3] Edit a group's popularity rules.")
+	fmt.Println("[4] Set a group's time zone.")
+	fmt.Println("[5] Choose how a group's memory is picked.")
+	menuScanner.Scan()
+	selection := menuScanner.Text()
+	if menuScanner.Err() != nil {
+		fmt.Println(menuScanner.Err())
+	}
+	if selection == "1" {
+		botCreationMenu(groups)
+	} else if selection == "2" {
+		botDeletionMenu(groups)
+	} else if selection == "3" {
+		popularityRulesMenu(groups)
+	} else if selection == "4" {
+		timeZoneMenu(groups)
+	} else if selection == "5" {
+		selectionRulesMenu(groups)
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatalln(err)
+func botCreationMenu(groups []Group) {
+	fmt.Println("\n\nHere are all the groups you are a member of. Enter the number corresponding to the group you want to add a bot to: ")
+	groupIndex := menuHelper(groups)
+	if groupIndex < 0 {
+		return
 	}
-	bot := BotCreationResponse{}
-	err = json.Unmarshal(body, &bot)
+	_, err := addBotToGroup(groups[groupIndex].GroupID)
 	if err != nil {
-		log.Fatalln(err)
+		fmt.Println(err)
 	}
-	return bot.Response.Info.BotID
-
 }
 
-func deleteBot(botID, accessToken string) {
-	url := fmt.Sprintf("%s/bots/destroy?token=%s", urlBase, accessToken)
-	params := map[string]interface{}{
-		"bot_id": botID,
+func botDeletionMenu(groups []Group) {
+	fmt.Println("\n\nHere are all the groups you are a member of. Enter the number corresponding to the group you want to remove a bot from: ")
+	groupIndex := menuHelper(groups)
+	if groupIndex < 0 {
+		return
 	}
-	bytesRepresentation, err := json.Marshal(params)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(bytesRepresentation))
+	err := removeBotFromGroup(groups[groupIndex].GroupID)
 	if err != nil {
-		log.Fatalln(err)
+		fmt.Println(err)
 	}
-	defer resp.Body.Close()
 }
 
-func handler() {
-	gotenv.Load()
-	accessToken := os.Getenv("ACCESS_TOKEN")
-	log.Print("Getting groups...")
-	groups := getAllGroups(accessToken)
-	log.Print(fmt.Sprintf("Got %d groups.", len(groups)))
-	if menu {
-		log.Print(fmt.Sprintf("Accessing the menu"))
-		showMenu(groups, accessToken)
-	} else if local {
-		log.Print(fmt.Sprintf("Local run..."))
-		sendMessages(accessToken)
+var errBotAlreadyInGroup = errors.New("that group already has this bot")
+var errNoBotInGroup = errors.New("that group doesn't have this bot")
 
-	} else {
-		log.Print(fmt.Sprintf("Sending messages..."))
-		sendMessages(accessToken)
-		cloudwatchTrigger.UpdateTrigger()
+//addBotToGroup creates a bot in the group and saves it, returning the new bot id
+func addBotToGroup(groupID string) (string, error) {
+	if store.GetBotForGroup(groupID) != "" {
+		return "", errBotAlreadyInGroup
 	}
-}
-
-func showMenu(groups []Group, accessToken string) {
-	fmt.Println("Make a selection:")
-	fmt.Println("[1] Add the bot to a group.")
-	fmt.Println("[2] Remove the bot from a group.")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	selection := scanner.Text()
-	if scanner.Err() != nil {
-		fmt.Println(scanner.Err())
+	groupConfig := groupSettings(groupID)
+	botID, err := groupMe.createBot(groupID, groupConfig.Name, groupConfig.AvatarURL, appConfig.CallbackURL)
+	if err != nil {
+		return "", err
 	}
-	if selection == "1" {
-		botCreationMenu(groups, accessToken)
-	} else if selection == "2" {
-		botDeletionMenu(groups, accessToken)
+	err = store.AddBot(groupID, botID)
+	if err != nil {
+		return "", err
 	}
+	return botID, nil
 }
 
-func botCreationMenu(groups []Group, accessToken string) {
-	fmt.Println("\n\nHere are all the groups you are a member of. Enter the number corresponding to the group you want to add a bot to: ")
-	groupIndex := menuHelper(groups)
-	groupID := groups[groupIndex].GroupID
-	botID := dbConnection.GetBotForGroup(groupID)
+//removeBotFromGroup deletes the group's bot from GroupMe and from the store
+func removeBotFromGroup(groupID string) error {
+	botID := store.GetBotForGroup(groupID)
 	if botID == "" {
-		botID = createBot(groupID, accessToken)
-		dbConnection.AddBot(groupID, botID)
-	} else {
-		fmt.Println("That group already has this bot.")
+		return errNoBotInGroup
 	}
+	err := groupMe.deleteBot(botID)
+	if err != nil {
+		return err
+	}
+	store.RemoveBot(groupID)
+	return nil
 }
 
-func botDeletionMenu(groups []Group, accessToken string) {
-	fmt.Println("\n\nHere are all the groups you are a member of. Enter the number corresponding to the group you want to remove a bot from: ")
+func popularityRulesMenu(groups []Group) {
+	fmt.Println("\n\nHere are all the groups you are a member of. Enter the number corresponding to the group you want to edit the popularity rules of: ")
 	groupIndex := menuHelper(groups)
+	if groupIndex < 0 {
+		return
+	}
 	groupID := groups[groupIndex].GroupID
-	botID := dbConnection.GetBotForGroup(groupID)
-	if botID == "" {
+	item, ok, err := store.GetItem(groupID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if !ok {
 		fmt.Println("That group doesn't have this bot.")
+		return
+	}
+	rules := dbConnection.PopularityRules{}
+	if item.PopularityRules != nil {
+		rules = *item.PopularityRules
+		fmt.Println(fmt.Sprintf("Current rules: %s", describePopularityRules(item.PopularityRules)))
 	} else {
-		dbConnection.RemoveBot(groupID)
-		deleteBot(botID, accessToken)
+		fmt.Println("This group uses the default rules.")
+	}
+	if promptMenu("Go back to the default rules? (y/n)") == "y" {
+		item.PopularityRules = nil
+		if err := store.SaveItem(item); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	fmt.Println("Leave any answer blank to keep its current value. 0 turns a rule off.")
+	if answer := promptMenu(fmt.Sprintf("Minimum likes [%d]:", rules.MinLikes)); answer != "" {
+		if value, err := strconv.Atoi(answer); err == nil {
+			rules.MinLikes = value
+		} else {
+			fmt.Println(err)
+		}
+	}
+	if answer := promptMenu(fmt.Sprintf("Minimum percentage of members who liked it [%g]:", rules.MinLikePercentage)); answer != "" {
+		if value, err := strconv.ParseFloat(answer, 32); err == nil {
+			rules.MinLikePercentage = float32(value)
+		} else {
+			fmt.Println(err)
+		}
+	}
+	if answer := promptMenu(fmt.Sprintf("Minimum percentile of the group's like counts [%g]:", rules.LikePercentile)); answer != "" {
+		if value, err := strconv.ParseFloat(answer, 32); err == nil {
+			rules.LikePercentile = float32(value)
+		} else {
+			fmt.Println(err)
+		}
+	}
+	if answer := promptMenu(fmt.Sprintf("Excluded phrases, separated by commas, or - for none [%s]:", strings.Join(rules.ExcludedPhrases, ", "))); answer == "-" {
+		rules.ExcludedPhrases = nil
+	} else if answer != "" {
+		rules.ExcludedPhrases = nil
+		for _, phrase := range strings.Split(answer, ",") {
+			if phrase = strings.TrimSpace(phrase); phrase != "" {
+				rules.ExcludedPhrases = append(rules.ExcludedPhrases, phrase)
+			}
+		}
+	}
+	if !hasThreshold(&rules) {
+		fmt.Println("At least one of minimum likes, percentage or percentile has to be above 0, or every message would be popular. Nothing was saved.")
+		return
+	}
+	item.PopularityRules = &rules
+	if err := store.SaveItem(item); err != nil {
+		fmt.Println(err)
+		return
 	}
+	fmt.Println(fmt.Sprintf("Saved rules: %s", describePopularityRules(item.PopularityRules)))
 }
 
+func promptMenu(prompt string) string {
+	fmt.Println(prompt)
+	menuScanner.Scan()
+	if menuScanner.Err() != nil {
+		fmt.Println(menuScanner.Err())
+	}
+	return strings.TrimSpace(menuScanner.Text())
+}
+
+//menuHelper lists the groups and asks until it gets one of their numbers. It returns -1 if stdin runs out first
 func menuHelper(groups []Group) int {
 	fmt.Println("-------------------------------------------------------------------------------------------------------------------")
 	for i, group := range groups {
 		fmt.Println(fmt.Sprintf("[%d] %s", i, group.Name))
 	}
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	selectedGroup := scanner.Text()
-	if scanner.Err() != nil {
-		fmt.Println(scanner.Err())
+	for menuScanner.Scan() {
+		groupIndex, err := strconv.Atoi(strings.TrimSpace(menuScanner.Text()))
+		if err == nil && groupIndex >= 0 && groupIndex < len(groups) {
+			return groupIndex
+		}
+		fmt.Println(fmt.Sprintf("Enter a number from 0 to %d:", len(groups)-1))
 	}
-	var groupIndex int
-	if index, err := strconv.Atoi(selectedGroup); err == nil {
-		groupIndex = index
-	} else {
-		fmt.Println(err)
+	if menuScanner.Err() != nil {
+		fmt.Println(menuScanner.Err())
 	}
-	return groupIndex
+	return -1
 }
 
-func sendMessages(accessToken string) {
+func sendMessages() error {
 	log.Print("Initiating...")
-	item := dbConnection.Item{}
-	loc, _ := time.LoadLocation(location)
-	currentTime := time.Now().In(loc)
-	hour, min, _ := currentTime.Clock()
-	_, month, day := currentTime.Date()
-	timeAndDate := fmt.Sprintf("Current time is %d:%d and the date is %d/%d", hour, min, month, day)
-	log.Print(timeAndDate)
-
-	log.Print(fmt.Sprintf("Location is set as: %s", currentTime.Location().String()))
-	log.Print(fmt.Sprintf("Local is: %s", currentTime.Local().String()))
-
-	allItemsFromDatabase := dbConnection.GetAllItems().Items
+
+	allItemsFromDatabase, err := store.GetAllItems()
+	if err != nil {
+		return err
+	}
 	log.Print(fmt.Sprintf("%d items found in database", len(allItemsFromDatabase)))
 
-	findTestGroup(allItemsFromDatabase, accessToken)
+	report := sendMessagesForItems(allItemsFromDatabase, []runTime{runningAt(time.Now())})
+	if dryRun {
+		printCandidates(os.Stdout, report.candidates(), dryRunFormat)
+	}
+	return report.err()
+}
+
+//sendMessagesForItems runs every item's group for each of days, in order, and logs the report
+func sendMessagesForItems(items []dbConnection.Item, days []runTime) runReport {
+	findTestGroup(items)
 
-	for _, i := range allItemsFromDatabase {
-		err := dynamodbattribute.UnmarshalMap(i, &item)
-		if err != nil {
-			log.Print("Got error unmarshalling. System will exit.")
-			log.Print(err.Error())
-			os.Exit(1)
+	report := runAllGroups(items, appConfig.Parallelism, func(item dbConnection.Item, report *groupReport) error {
+		return sendMessageForGroup(item, days, report)
+	})
+	report.log()
+	return report
+}
+
+//runTime is the moment a run is for, in a group's own location
+type runTime func(loc *time.Location) time.Time
+
+//runningAt is a run for the instant now, whatever date that is in each group
+func runningAt(now time.Time) runTime {
+	return func(loc *time.Location) time.Time {
+		return now.In(loc)
+	}
+}
+
+//runningFromToday is a run for each of count days starting with now's date in each group, so every group starts from its own today
+func runningFromToday(now time.Time, count int) []runTime {
+	var days []runTime
+	for i := 0; i < count; i++ {
+		offset := i
+		days = append(days, func(loc *time.Location) time.Time {
+			return now.In(loc).AddDate(0, 0, offset)
+		})
+	}
+	return days
+}
+
+//runningOn is a run for year/month/day in every group, no matter what the date is there right now
+func runningOn(year int, month time.Month, day int) runTime {
+	now := time.Now()
+	return func(loc *time.Location) time.Time {
+		hour, min, sec := now.In(loc).Clock()
+		return time.Date(year, month, day, hour, min, sec, 0, loc)
+	}
+}
+
+//runningFromTo is a run for every date from from to to, both included. Only the dates of from and to matter
+func runningFromTo(from, to time.Time) []runTime {
+	var days []runTime
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		days = append(days, runningOn(date.Date()))
+	}
+	return days
+}
+
+//groupRunner does one group's part of a run, filling in its report
+type groupRunner func(item dbConnection.Item, report *groupReport) error
+
+//runAllGroups runs runGroup for every item with at most parallelism groups going at once.
+//Results are kept in the same order as items no matter which group finishes first
+func runAllGroups(items []dbConnection.Item, parallelism int, runGroup groupRunner) runReport {
+	start := time.Now()
+	report := runReport{Groups: make([]groupReport, len(items))}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	jobs := make(chan int)
+	var wait sync.WaitGroup
+	for worker := 0; worker < parallelism; worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for i := range jobs {
+				groupStart := time.Now()
+				groupReport := &report.Groups[i]
+				groupReport.GroupID = items[i].GroupId
+				err := runGroup(items[i], groupReport)
+				if err != nil { //one group failing shouldn't stop the rest from getting their memories
+					log.Print(fmt.Sprintf("Error reached when sending the message for group %s, moving on.", items[i].GroupId))
+					log.Print(err)
+					groupReport.Err = err
+				}
+				groupReport.Duration = time.Since(groupStart)
+			}
+		}()
+	}
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wait.Wait()
+	report.Duration = time.Since(start)
+	return report
+}
+
+//sendMessageForGroup posts one memory for each of days in one group, or in a dry run adds their candidates to the report instead
+func sendMessageForGroup(item dbConnection.Item, days []runTime, report *groupReport) error {
+	group, err := groupMe.getGroup(item.GroupId)
+	if err != nil {
+		return err
+	}
+	report.Group = group.Name
+	log.Print(fmt.Sprintf("Got group with name %s and id %s.", group.Name, group.GroupID))
+	loc := groupSettings(group.GroupID).Loc()
+	var dates []time.Time
+	for _, at := range days {
+		dates = append(dates, at(loc))
+	}
+	log.Print(fmt.Sprintf("Location is set as: %s", loc.String()))
+	finder, err := newCandidateFinder(group)
+	if err != nil {
+		return err
+	}
+	selection := newSelection(item.SelectionRules, loc, store.GetReposts(group.GroupID))
+	if local {
+		item.BotId = testGroupBotID
+	}
+	for _, currentTime := range dates {
+		hour, min, _ := currentTime.Clock()
+		_, month, day := currentTime.Date()
+		log.Print(fmt.Sprintf("Current time is %d:%d and the date is %d/%d", hour, min, month, day))
+		popularMessagesFromToday := finder.fromDate(currentTime)
+		log.Print(fmt.Sprintf("Found %d popular messages from %d/%d for group %s", len(popularMessagesFromToday), month, day, group.Name))
+		intro := ""
+		if len(popularMessagesFromToday) == 0 {
+			popularMessagesFromToday, intro = fallbackCandidates(finder, selection.rules.Fallback, currentTime)
+			log.Print(fmt.Sprintf("Falling back to %d messages with the %q fallback", len(popularMessagesFromToday), selection.rules.Fallback))
+		}
+		report.Candidates += len(popularMessagesFromToday)
+		messagesToPost := getMessagesToPost(selection, &popularMessagesFromToday, selection.memoriesPerRun())
+		if dryRun {
+			report.candidates = append(report.candidates, reportCandidates(group, selection, currentTime, popularMessagesFromToday, messagesToPost...)...)
+			if len(messagesToPost) == 0 && selection.rules.Fallback == "note" {
+				report.preview = strings.TrimSpace(report.preview + "\n" + noMemoryNote(currentTime))
+			}
+			continue
 		}
-		var popularMessagesFromToday []Message
-		group := getGroup(item.GroupId, accessToken)
-		log.Print(fmt.Sprintf("Got group with name %s and id %s.", group.Name, group.GroupID))
-		popularMessagesFromToday = getPopularMessagesFromDate(group, accessToken, currentTime)
-		log.Print(fmt.Sprintf("Found %d popular messages from today for group %s", len(popularMessagesFromToday), group.Name))
-		messageToPost := getMessageToPost(&popularMessagesFromToday)
-		if messageToPost.numLikes() > 0 { //checking to see if the message returned was a default message object or if its a real message
+		if len(messagesToPost) == 0 && selection.rules.Fallback == "note" {
+			log.Print(fmt.Sprintf("No memories from %d/%d for group %s, posting a note instead", month, day, group.Name))
+			err = groupMe.postBotMessage(item.BotId, noMemoryNote(currentTime), nil)
+			if err != nil {
+				return err
+			}
+		}
+		for part, messageToPost := range messagesToPost {
 			log.Print(fmt.Sprintf("Posting message: '%s' by %s", messageToPost.Text, messageToPost.Name))
-			if local {
-				item.BotId = testGroupBotID
+			err = postMessage(messageToPost, item.BotId, memoryHeading(intro, part, len(messagesToPost)))
+			if err != nil {
+				return err
 			}
-			postMessage(messageToPost, item.BotId)
+			report.Posted = append(report.Posted, messageToPost.MessageID)
+			selection.featured(messageToPost)
 			if !local {
-				dbConnection.UpdateLastMessageId(group.GroupID, messageToPost.MessageID)
+				store.UpdateLastMessageId(group.GroupID, messageToPost.MessageID)
+				err = recordRepost(group.GroupID, messageToPost)
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
+	return nil
 }
 
-func findTestGroup(dbItems []map[string]*dynamodb.AttributeValue, accessToken string) {
+func findTestGroup(dbItems []dbConnection.Item) {
 	if !local {
 		return
 	}
-	item := dbConnection.Item{}
-	for _, i := range dbItems {
-		err := dynamodbattribute.UnmarshalMap(i, &item)
+	for _, item := range dbItems {
+		group, err := groupMe.getGroup(item.GroupId)
 		if err != nil {
-			log.Print("Got error unmarshalling. System will exit.")
-			log.Print(err.Error())
-			os.Exit(1)
+			log.Print(err)
+			continue
 		}
-		group := getGroup(item.GroupId, accessToken)
-		if group.Name == "Test Group" {
+		if group.Name == appConfig.TestGroupName {
 			testGroupBotID = item.BotId
 			log.Print(fmt.Sprintf("Found test group %s, making the test bot id %s", group.ID, item.BotId))
 			return
@@ -573,50 +774,95 @@ func findTestGroup(dbItems []map[string]*dynamodb.AttributeValue, accessToken st
 
 }
 
-func getGroup(groupID, accessToken string) Group {
-	url := fmt.Sprintf("%s/groups/%s?token=%s", urlBase, groupID, accessToken)
-	resp, err := http.Get(url)
+func main() {
+	menuFlag := flag.Bool("menu", false, "boolean to bring up the menu. Takes highest priority of the flags.")
+	serveFlag := flag.Bool("serve", false, "boolean to run the callback server locally")
+	portFlag := flag.String("port", "8080", "port for the local callback server")
+	localFlag := flag.Bool("local", false, "boolean to run locally (but not to bring up the menu)")
+	dryRunFlag := flag.Bool("dry-run", false, "boolean to print every candidate and the chosen memory without posting or saving anything")
+	formatFlag := flag.String("format", "table", "output format for -dry-run, table or json")
+	migrateRepostsFlag := flag.Bool("migrate-reposts", false, "boolean to backfill repost records from the bot's old posts, then exit")
+	configFlag := flag.String("config", "", "path to a YAML config file. Defaults to config.yaml if it exists")
+
+	flag.Parse()
+
+	gotenv.Load()
+	configPath := *configFlag
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG_PATH")
+	}
+	var err error
+	if configPath == "" {
+		appConfig, err = config.Load("config.yaml", false)
+	} else {
+		appConfig, err = config.Load(configPath, true)
+	}
 	if err != nil {
-		log.Print("Fatal error reached when getting group.")
+		log.Print("Fatal error reached when loading the config.")
 		log.Fatalln(err)
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	groupMe = newGroupMeAPI(os.Getenv("ACCESS_TOKEN"), appConfig.APIBaseURL, appConfig.RequestsPerSecond)
+	archiveStorage, err := newArchiveStorage(appConfig.ArchiveBackend, appConfig.ArchivePath, appConfig.ArchiveBucket, appConfig.Store.Region)
 	if err != nil {
-		log.Print("Fatal error reached when reading after getting group.")
+		log.Print("Fatal error reached when opening the archive.")
 		log.Fatalln(err)
 	}
-	group := OneGroup{}
-	err = json.Unmarshal(body, &group)
+	archive = newArchive(archiveStorage)
+	store, err = dbConnection.NewStore(dbConnection.Config{
+		Backend:          appConfig.Store.Backend,
+		TableName:        appConfig.Store.Table,
+		RepostsTableName: appConfig.Store.RepostsTable,
+		Region:           appConfig.Store.Region,
+		Path:             appConfig.Store.Path,
+	})
 	if err != nil {
-		log.Print("Fatal error reached when unmarshaling group.")
+		log.Print("Fatal error reached when opening the store.")
 		log.Fatalln(err)
-		panic(err)
 	}
-	return group.Group
-}
-
-func main() {
-	menuFlag := flag.Bool("menu", false, "boolean to bring up the menu. Takes highest priority of the flags.")
-	localFlag := flag.Bool("local", false, "boolean to run locally (but not to bring up the menu)")
-
-	flag.Parse()
-
-	gotenv.Load()
-	if *menuFlag {
+	randomizer = scheduler.NewRandomizer(appConfig.Schedule.Seed)
+	jobScheduler = newJobScheduler()
+	if flag.NArg() > 0 {
+		local = *localFlag
+		dryRun = *dryRunFlag
+		os.Exit(runSubcommand(flag.Args(), os.Stdout))
+	} else if *migrateRepostsFlag {
+		log.Print("Migrating reposts...")
+		migrateReposts()
+	} else if *menuFlag {
 		menu = true
 		log.Print("Bringing up menu...")
-		accessToken := os.Getenv("ACCESS_TOKEN")
 		log.Print("Getting groups...")
-		groups := getAllGroups(accessToken)
+		groups, err := getAllGroups()
+		if err != nil {
+			log.Print("Fatal error reached when getting groups.")
+			log.Fatalln(err)
+		}
 		log.Print(fmt.Sprintf("Got %d groups.", len(groups)))
-		showMenu(groups, accessToken)
+		showMenu(groups)
+	} else if *serveFlag {
+		log.Print(fmt.Sprintf("Serving callbacks on port %s...", *portFlag))
+		runCallbackServer(*portFlag)
+	} else if *dryRunFlag {
+		dryRun = true
+		dryRunFormat = *formatFlag
+		log.Print(fmt.Sprintf("Dry run..."))
+		err = handler(scheduledRun{})
 	} else if *localFlag {
 		local = true
 		log.Print(fmt.Sprintf("Running locally..."))
-		handler()
+		err = handler(scheduledRun{})
+	} else if appConfig.Schedule.Backend == "cron" {
+		log.Print("Running the scheduler...")
+		runCron()
+	} else if os.Getenv("LAMBDA_HANDLER") == "callback" {
+		log.Print("Handling callbacks in prod...")
+		lambda.Start(callbackHandler)
 	} else {
 		log.Print("Running in prod...")
 		lambda.Start(handler)
 	}
+	if err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
 }
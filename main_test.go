@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+//newTestGroup points the bot at a fake GroupMe, a memory store and an archive in a temp dir, with one group that has the bot
+func newTestGroup(t *testing.T, groupID string, members ...string) *fakeGroupMe {
+	fake := newFakeGroupMe()
+	groupMe = fake
+	store = dbConnection.NewMemoryStore()
+	archive = newMessageArchive(t.TempDir())
+	fake.addGroup(groupID, "Group", members...)
+	botID, err := fake.createBot(groupID, "MemsBot", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.AddBot(groupID, botID)
+	return fake
+}
+
+func TestSendMessagesPostsAMemory(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	now := time.Now().In(appConfig.Bot.Loc())
+	fake.addMessage("g1", "b", "not popular", now.AddDate(-2, 0, 0), "a")
+	messageID := fake.addMessage("g1", "a", "hello", now.AddDate(-2, 0, 0), "a", "b", "c", "d")
+
+	err := sendMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	posted := fake.postedMessages()
+	if len(posted) != 1 || !strings.Contains(posted[0].Text, "hello") {
+		t.Fatalf("posted %+v, want one memory of hello", posted)
+	}
+	if lastMessageID := store.GetLastMessageIdForGroup("g1"); lastMessageID != messageID {
+		t.Errorf("last message id is %q, want %q", lastMessageID, messageID)
+	}
+	reposts := store.GetReposts("g1")
+	if len(reposts) != 1 || reposts[0].MessageId != messageID || reposts[0].Likes != 4 {
+		t.Errorf("recorded %+v, want one repost of %s with 4 likes", reposts, messageID)
+	}
+}
+
+func TestRunOnAPastDateOnlyPostsEarlierYears(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	loc := appConfig.Bot.Loc()
+	fake.addMessage("g1", "a", "from before", time.Date(2019, 7, 4, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "b", "from after", time.Date(2024, 7, 4, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+
+	var out bytes.Buffer
+	if code := runSubcommand([]string{"run", "--date", "2021-07-04"}, &out); code != 0 {
+		t.Fatalf("run exited %d: %s", code, out.String())
+	}
+	posted := fake.postedMessages()
+	if len(posted) != 1 || !strings.Contains(posted[0].Text, "from before") {
+		t.Fatalf("posted %+v, want only the memory from 2019", posted)
+	}
+}
+
+func TestRunningFromTodayUsesEachGroupsDate(t *testing.T) {
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2021, 3, 14, 3, 0, 0, 0, time.UTC)
+	days := runningFromToday(now, 2)
+	for _, test := range []struct {
+		loc  *time.Location
+		days []int
+	}{
+		{time.UTC, []int{14, 15}},
+		{losAngeles, []int{13, 14}},
+	} {
+		for i, day := range days {
+			if date := day(test.loc); date.Day() != test.days[i] || date.Location() != test.loc {
+				t.Errorf("day %d in %s is %s, want March %d there", i, test.loc, date, test.days[i])
+			}
+		}
+	}
+}
+
+//failingStore is a memory store whose item reads fail, for checking that store errors fail the run
+type failingStore struct {
+	*dbConnection.MemoryStore
+	listErr error
+	getErr  error
+}
+
+func (store failingStore) GetAllItems() ([]dbConnection.Item, error) {
+	if store.listErr != nil {
+		return nil, store.listErr
+	}
+	return store.MemoryStore.GetAllItems()
+}
+
+func (store failingStore) GetItem(groupId string) (dbConnection.Item, bool, error) {
+	if store.getErr != nil {
+		return dbConnection.Item{}, false, store.getErr
+	}
+	return store.MemoryStore.GetItem(groupId)
+}
+
+func TestStoreErrorsFailTheRun(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	fake.addMessage("g1", "a", "hello", time.Now().AddDate(-2, 0, 0), "a", "b", "c", "d")
+	memoryStore := store.(*dbConnection.MemoryStore)
+	scanErr := errors.New("scan failed")
+
+	store = failingStore{MemoryStore: memoryStore, listErr: scanErr}
+	if err := sendMessages(); !errors.Is(err, scanErr) {
+		t.Errorf("a failed scan got %v, want it returned", err)
+	}
+	if code := runSubcommand([]string{"run"}, &bytes.Buffer{}); code == 0 {
+		t.Error("run exited 0 when the scan failed")
+	}
+
+	store = failingStore{MemoryStore: memoryStore, getErr: errors.New("get failed")}
+	report := sendMessagesForItems([]dbConnection.Item{{GroupId: "g1"}}, []runTime{runningAt(time.Now())})
+	if report.err() == nil || report.Groups[0].Err == nil {
+		t.Errorf("a failed item read got %+v, want the group to fail", report.Groups)
+	}
+	if len(fake.postedMessages()) != 0 {
+		t.Errorf("posted %+v after the store failed", fake.postedMessages())
+	}
+}
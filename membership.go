@@ -0,0 +1,61 @@
+package main
+
+import "sort"
+
+//membershipTimeline is how many members a group had after each membership event, rebuilt from the system
+//messages GroupMe posts whenever someone is added, removed, leaves or joins by share link
+type membershipTimeline struct {
+	times   []int64 //when each event happened, oldest first
+	counts  []int   //members right after the event at the same index
+	initial int     //members before the first event
+}
+
+//membershipChange is how much an event changed the member count
+func membershipChange(event Event) int {
+	switch event.Type {
+	case "membership.announce.added":
+		return len(event.Data.AddedUsers)
+	case "membership.announce.joined", "membership.announce.rejoined":
+		return 1
+	case "membership.notifications.removed", "membership.notifications.exited":
+		return -1
+	}
+	return 0
+}
+
+//buildMembershipTimeline walks messages, newest first, backwards from the current member count
+func buildMembershipTimeline(currentMembers int, messages []*Message) membershipTimeline {
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].TimeSent > messages[j].TimeSent
+	})
+	timeline := membershipTimeline{}
+	count := currentMembers
+	for _, message := range messages {
+		change := membershipChange(message.Event)
+		if change == 0 {
+			continue
+		}
+		timeline.times = append(timeline.times, message.TimeSent)
+		timeline.counts = append(timeline.counts, count)
+		count -= change
+		if count < 1 {
+			count = 1
+		}
+	}
+	for i, j := 0, len(timeline.times)-1; i < j; i, j = i+1, j-1 {
+		timeline.times[i], timeline.times[j] = timeline.times[j], timeline.times[i]
+		timeline.counts[i], timeline.counts[j] = timeline.counts[j], timeline.counts[i]
+	}
+	timeline.initial = count
+	return timeline
+}
+
+func (timeline membershipTimeline) membersAt(timeSent int64) int {
+	index := sort.Search(len(timeline.times), func(i int) bool {
+		return timeline.times[i] > timeSent
+	})
+	if index == 0 {
+		return timeline.initial
+	}
+	return timeline.counts[index-1]
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestMembersAt(t *testing.T) {
+	event := func(timeSent int64, eventType string, added int) *Message {
+		return &Message{TimeSent: timeSent, Event: Event{Type: eventType, Data: EventData{AddedUsers: make([]EventUser, added)}}}
+	}
+	messages := []*Message{ //out of order, like they can be after a sync
+		event(300, "membership.notifications.removed", 0),
+		event(100, "membership.announce.added", 2),
+		event(500, "membership.announce.rejoined", 0),
+		event(200, "membership.announce.joined", 0),
+		event(350, "membership.nickname_changed", 0),
+		{TimeSent: 375, Text: "just a message"},
+		event(400, "membership.notifications.exited", 0),
+	}
+	timeline := buildMembershipTimeline(5, messages)
+	for _, test := range []struct {
+		timeSent int64
+		members  int
+	}{
+		{50, 3},  //before anyone was added
+		{100, 5}, //two added
+		{150, 5},
+		{200, 6}, //one joined
+		{300, 5}, //one removed
+		{350, 5}, //a name change isn't a member more or less
+		{375, 5},
+		{400, 4}, //one left
+		{500, 5}, //they came back
+		{600, 5},
+	} {
+		if members := timeline.membersAt(test.timeSent); members != test.members {
+			t.Errorf("membersAt(%d) = %d, want %d", test.timeSent, members, test.members)
+		}
+	}
+}
+
+func TestMembersAtNeverDropsBelowOne(t *testing.T) {
+	timeline := buildMembershipTimeline(1, []*Message{
+		{TimeSent: 100, Event: Event{Type: "membership.announce.added", Data: EventData{AddedUsers: make([]EventUser, 3)}}},
+	})
+	if members := timeline.membersAt(50); members != 1 {
+		t.Errorf("membersAt before the add = %d, want 1", members)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const fallbackSearchDays = 14 //how far the nearest fallback looks either side of the date
+const allTimeTopN = 10        //how many of the most liked messages the top fallback picks between
+
+var memoryFallbacks = []string{"none", "nearest", "top", "note"}
+
+func validMemoryFallback(fallback string) bool {
+	for _, known := range memoryFallbacks {
+		if fallback == known {
+			return true
+		}
+	}
+	return fallback == ""
+}
+
+//memoriesPerRun is how many memories the group gets each run, one unless its selection rules say otherwise
+func (selection selection) memoriesPerRun() int {
+	if selection.rules.Count < 1 {
+		return 1
+	}
+	return selection.rules.Count
+}
+
+//getMessagesToPost picks up to count different messages, one draw at a time, and returns them oldest first so they
+//read like a thread. It stops early once nothing left could be picked
+func getMessagesToPost(selection selection, messages *[]Message, count int) []Message {
+	var picked []Message
+	remaining := append([]Message(nil), *messages...)
+	for len(picked) < count && len(remaining) > 0 {
+		if len(picked) > 0 && totalWeight(selection.weights(remaining)) == 0 {
+			break
+		}
+		message := getMessageToPost(selection, &remaining)
+		picked = append(picked, message)
+		for i := range remaining {
+			if remaining[i].MessageID == message.MessageID {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	sort.SliceStable(picked, func(i, j int) bool {
+		return picked[i].TimeSent < picked[j].TimeSent
+	})
+	return picked
+}
+
+func totalWeight(weights []float64) float64 {
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	return total
+}
+
+//fallbackCandidates is what to pick from when nothing from date qualifies, and the line introducing it.
+//nearest goes to the closest day with a popular message, later days first on a tie, and top to the all-time favorites
+func fallbackCandidates(finder candidateFinder, fallback string, date time.Time) ([]Message, string) {
+	switch fallback {
+	case "nearest":
+		for offset := 1; offset <= fallbackSearchDays; offset++ {
+			for _, nearby := range []time.Time{date.AddDate(0, 0, offset), date.AddDate(0, 0, -offset)} {
+				if candidates := finder.fromDate(nearby); len(candidates) > 0 {
+					return candidates, fmt.Sprintf("Nothing from %s in past years, so here's %s instead:", describeDates([]time.Time{date}), describeDates([]time.Time{nearby}))
+				}
+			}
+		}
+	case "top":
+		if candidates := finder.allTime(date, allTimeTopN); len(candidates) > 0 {
+			return candidates, fmt.Sprintf("Nothing from %s in past years, so here's an all-time favorite:", describeDates([]time.Time{date}))
+		}
+	}
+	return nil, ""
+}
+
+//noMemoryNote is what the note fallback posts when there's nothing to repost
+func noMemoryNote(date time.Time) string {
+	return fmt.Sprintf("Nothing from %s in past years. Check back tomorrow!", describeDates([]time.Time{date}))
+}
+
+//memoryHeading goes above the part'th of parts memories in a run, numbering them when there's more than one
+func memoryHeading(intro string, part, parts int) string {
+	heading := ""
+	if part == 0 {
+		heading = intro
+	}
+	if parts > 1 {
+		if heading != "" {
+			heading += "\n"
+		}
+		heading += fmt.Sprintf("(%d/%d)", part+1, parts)
+	}
+	return heading
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+//runOn runs every group for date, failing the test if the run fails
+func runOn(t *testing.T, date string) {
+	var out bytes.Buffer
+	if code := runSubcommand([]string{"run", "--date", date}, &out); code != 0 {
+		t.Fatalf("run exited %d: %s", code, out.String())
+	}
+}
+
+func TestNearestFallbackPicksTheClosestDay(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	loc := appConfig.Bot.Loc()
+	fake.addMessage("g1", "a", "two days before", time.Date(2019, 7, 2, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "a", "two days after", time.Date(2019, 7, 6, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "a", "a day after but unliked", time.Date(2019, 7, 5, 12, 0, 0, 0, loc))
+	err := setSelectionRules("g1", &dbConnection.SelectionRules{Strategy: "lottery", Fallback: "nearest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runOn(t, "2021-07-04")
+	posted := fake.postedMessages()
+	if len(posted) != 1 || !strings.Contains(posted[0].Text, "two days after") || !strings.Contains(posted[0].Text, "instead") {
+		t.Fatalf("posted %+v, want the later of the two closest days with an intro", posted)
+	}
+}
+
+func TestNoteFallbackPostsANote(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	err := setSelectionRules("g1", &dbConnection.SelectionRules{Strategy: "lottery", Fallback: "note"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runOn(t, "2021-07-04")
+	posted := fake.postedMessages()
+	if len(posted) != 1 || posted[0].Text != noMemoryNote(time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("posted %+v, want the no memory note", posted)
+	}
+}
+
+func TestCountPostsSeveralMemoriesOldestFirst(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	loc := appConfig.Bot.Loc()
+	fake.addMessage("g1", "a", "newer", time.Date(2020, 7, 4, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "b", "older", time.Date(2018, 7, 4, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "c", "middle", time.Date(2019, 7, 4, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	err := setSelectionRules("g1", &dbConnection.SelectionRules{Strategy: "lottery", Count: 2, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runOn(t, "2021-07-04")
+	posted := fake.postedMessages()
+	if len(posted) != 2 {
+		t.Fatalf("posted %+v, want 2 memories", posted)
+	}
+	if !strings.HasPrefix(posted[0].Text, "(1/2)") || !strings.HasPrefix(posted[1].Text, "(2/2)") {
+		t.Errorf("posted %q and %q, want them numbered", posted[0].Text, posted[1].Text)
+	}
+	order := []string{"older", "middle", "newer"}
+	first, second := -1, -1
+	for i, text := range order {
+		if strings.Contains(posted[0].Text, text) {
+			first = i
+		}
+		if strings.Contains(posted[1].Text, text) {
+			second = i
+		}
+	}
+	if first < 0 || second <= first {
+		t.Errorf("posted %q then %q, want two different memories oldest first", posted[0].Text, posted[1].Text)
+	}
+}
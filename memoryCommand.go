@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+func init() {
+	registerCommand(Command{Name: "memory", Usage: "[year] [month]", Help: "posts a random popular message, from any time or just the year or month given", Handler: memoryCommand})
+}
+
+//memoryPeriod is what a !memory is picked from. Zero fields match anything
+type memoryPeriod struct {
+	Year  int
+	Month time.Month
+}
+
+func (period memoryPeriod) includes(sent time.Time) bool {
+	return (period.Year == 0 || sent.Year() == period.Year) && (period.Month == 0 || sent.Month() == period.Month)
+}
+
+func (period memoryPeriod) String() string {
+	switch {
+	case period.Year != 0 && period.Month != 0:
+		return fmt.Sprintf("%s %d", period.Month, period.Year)
+	case period.Year != 0:
+		return strconv.Itoa(period.Year)
+	case period.Month != 0:
+		return period.Month.String()
+	}
+	return "any time"
+}
+
+//parseMemoryPeriod reads a year, a month name or both, in either order, or a year and month like 2019-03 or 3/2019
+func parseMemoryPeriod(args []string) (memoryPeriod, error) {
+	period := memoryPeriod{}
+	var words []string
+	for _, arg := range args {
+		words = append(words, strings.FieldsFunc(arg, func(r rune) bool { return r == '-' || r == '/' })...)
+	}
+	if len(words) > 2 {
+		return period, fmt.Errorf("too many arguments")
+	}
+	for _, word := range words {
+		if number, err := strconv.Atoi(word); err == nil {
+			switch {
+			case number >= 1000 && period.Year == 0:
+				period.Year = number
+			case number >= 1 && number <= 12 && period.Month == 0 && len(words) == 2:
+				period.Month = time.Month(number)
+			default:
+				return period, fmt.Errorf("%q isn't a year or month", word)
+			}
+			continue
+		}
+		month, ok := parseMonthName(word)
+		if !ok || period.Month != 0 {
+			return period, fmt.Errorf("%q isn't a year or month", word)
+		}
+		period.Month = month
+	}
+	return period, nil
+}
+
+//parseMonthName reads a month's full name or its first three letters, ignoring case
+func parseMonthName(word string) (time.Month, bool) {
+	word = strings.ToLower(word)
+	for month := time.January; month <= time.December; month++ {
+		name := strings.ToLower(month.String())
+		if word == name || (len(word) >= 3 && strings.HasPrefix(name, word)) {
+			return month, true
+		}
+	}
+	return 0, false
+}
+
+//memoryCommand posts an archived popular message from before today, like the daily memory but on demand
+func memoryCommand(message Message, args []string) (string, error) {
+	period, err := parseMemoryPeriod(args)
+	if err != nil {
+		return fmt.Sprintf("%v. Try %smemory, %smemory 2019 or %smemory march 2019", err, commandPrefix, commandPrefix, commandPrefix), nil
+	}
+	item, ok, err := store.GetItem(message.GroupID)
+	if err != nil {
+		return "", err
+	}
+	if !ok || item.BotId == "" {
+		return "", errNoBotInGroup
+	}
+	settings := groupSettings(message.GroupID)
+	loc := settings.Loc()
+	now := time.Now().In(loc)
+	if reply, waiting := cooldownReply(item, settings.Cooldown(), now); waiting {
+		return reply, nil
+	}
+	if !archive.exists(message.GroupID) {
+		return notArchivedReply, nil
+	}
+	group, err := groupMe.getGroup(message.GroupID)
+	if err != nil {
+		return "", err
+	}
+	finder, err := loadCandidateFinder(group)
+	if err != nil {
+		return "", err
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	candidates := finder.matching(now, func(sent time.Time) bool {
+		return sent.Before(today) && period.includes(sent)
+	})
+	log.Print(fmt.Sprintf("Found %d popular messages from %s for a memory in group %s", len(candidates), period, group.Name))
+	if len(candidates) == 0 && period == (memoryPeriod{}) {
+		return "There aren't any memories to post right now.", nil
+	}
+	if len(candidates) == 0 {
+		return fmt.Sprintf("There aren't any memories from %s to post right now.", period), nil
+	}
+	selection := newSelection(item.SelectionRules, loc, store.GetReposts(group.GroupID))
+	memory := getMessageToPost(selection, &candidates)
+	//claimed before posting, so messages arriving meanwhile already see the cooldown
+	claimed, err := store.ClaimMemory(group.GroupID, now.Unix(), now.Add(-settings.Cooldown()).Unix())
+	if err != nil {
+		return "", err
+	}
+	if !claimed {
+		item, _, err = store.GetItem(group.GroupID)
+		if err != nil {
+			return "", err
+		}
+		if reply, waiting := cooldownReply(item, settings.Cooldown(), now); waiting {
+			return reply, nil
+		}
+		return fmt.Sprintf("Another %smemory was just posted.", commandPrefix), nil
+	}
+	err = postMessage(memory, commandBotID(item), "")
+	if err != nil {
+		return "", err
+	}
+	if !local {
+		store.UpdateLastMessageId(group.GroupID, memory.MessageID)
+		return "", recordRepost(group.GroupID, memory)
+	}
+	return "", nil
+}
+
+//cooldownReply tells the group how long until the next !memory, if the last one was too recent
+func cooldownReply(item dbConnection.Item, cooldown time.Duration, now time.Time) (string, bool) {
+	wait := time.Unix(item.LastMemoryAt, 0).Add(cooldown).Sub(now)
+	if item.LastMemoryAt == 0 || wait <= 0 {
+		return "", false
+	}
+	return fmt.Sprintf("The next %smemory can be posted in %s.", commandPrefix, describeWait(wait)), true
+}
+
+//describeWait is a wait rounded up to the minute, like "5 minutes" or "1 hour 30 minutes"
+func describeWait(wait time.Duration) string {
+	minutes := int(math.Ceil(wait.Minutes()))
+	plural := func(count int, unit string) string {
+		if count == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", count, unit)
+	}
+	if minutes < 60 {
+		return plural(minutes, "minute")
+	}
+	if minutes%60 == 0 {
+		return plural(minutes/60, "hour")
+	}
+	return plural(minutes/60, "hour") + " " + plural(minutes%60, "minute")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryCommandWaitsOutTheCooldown(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	fake.addMessage("g1", "a", "hello", time.Now().AddDate(-2, 0, 0), "a", "b", "c", "d")
+	fake.addMessage("g1", "b", "world", time.Now().AddDate(-3, 0, 0), "a", "b", "c", "d")
+
+	reply, err := dispatchCommand(Message{GroupID: "g1", Text: "!memory"})
+	if reply != notArchivedReply || err != nil {
+		t.Fatalf("!memory before the first sync got %q, %v", reply, err)
+	}
+	err = archive.sync("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err = dispatchCommand(Message{GroupID: "g1", Text: "!memory"})
+	if reply != "" || err != nil || len(fake.postedMessages()) != 1 {
+		t.Fatalf("!memory got %q, %v and posted %d, want one memory", reply, err, len(fake.postedMessages()))
+	}
+	reply, err = dispatchCommand(Message{GroupID: "g1", Text: "!memory"})
+	if !strings.HasPrefix(reply, "The next !memory can be posted in") || err != nil || len(fake.postedMessages()) != 1 {
+		t.Fatalf("a second !memory got %q, %v and posted %d, want a wait", reply, err, len(fake.postedMessages()))
+	}
+}
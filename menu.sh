@@ -1,2 +1,2 @@
 #!/bin/sh
-go run main.go --menu
\ No newline at end of file
+go run . --menu
\ No newline at end of file
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const numOnMessages = 5 //how many messages !on lists
+
+func init() {
+	registerCommand(Command{Name: "on", Usage: "<date>", Help: "lists the most liked messages from a day like 7/4 in every year, or from one like 7/4/2019 or 2019-07-04", Handler: onCommand})
+}
+
+//parseDay reads a month and day like 7/4 or 07-04, with an optional year like 7/4/19, 7/4/2019 or 2019-07-04.
+//year is 0 when none was given
+func parseDay(value string) (month time.Month, day int, year int, err error) {
+	invalid := fmt.Errorf("%q isn't a date like 7/4, 7/4/2019 or 2019-07-04", value)
+	var numbers []int
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '-' }) {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, 0, 0, invalid
+		}
+		numbers = append(numbers, number)
+	}
+	switch {
+	case len(numbers) == 2:
+		month, day = time.Month(numbers[0]), numbers[1]
+	case len(numbers) == 3 && strings.Contains(value, "-") && numbers[0] >= 1000:
+		year, month, day = numbers[0], time.Month(numbers[1]), numbers[2]
+	case len(numbers) == 3:
+		month, day, year = time.Month(numbers[0]), numbers[1], numbers[2]
+		if year < 100 {
+			year += 2000
+		}
+	default:
+		return 0, 0, 0, invalid
+	}
+	checkYear := year
+	if checkYear == 0 {
+		checkYear = 2000 //a leap year, so 2/29 is allowed
+	}
+	date := time.Date(checkYear, month, day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != month || date.Day() != day {
+		return 0, 0, 0, invalid
+	}
+	return month, day, year, nil
+}
+
+//popularOn is the popular messages sent on month and day in the group's location, in year or every year if it's 0, most liked first.
+//Unlike the daily memory this is a lookup, so messages reposted lately still count
+func (finder candidateFinder) popularOn(month time.Month, day int, year int) []Message {
+	var popularMessages []Message
+	for _, message := range archive.messagesFromDate(finder.group.GroupID, finder.groupConfig.Loc(), month, day) {
+		if year != 0 && time.Unix(message.TimeSent, 0).In(finder.groupConfig.Loc()).Year() != year {
+			continue
+		}
+		if strings.Contains(message.Event.Type, "bot") || message.SenderType == "bot" {
+			continue
+		}
+		message.numMembersAtTime = finder.timeline.membersAt(message.TimeSent)
+		if message.isPopular(finder.popularity) {
+			popularMessages = append(popularMessages, *message)
+		}
+	}
+	sort.SliceStable(popularMessages, func(i, j int) bool {
+		return popularMessages[i].numLikes() > popularMessages[j].numLikes()
+	})
+	return popularMessages
+}
+
+func onCommand(message Message, args []string) (string, error) {
+	if len(args) != 1 {
+		return fmt.Sprintf("Give one date, like %son 7/4 or %son 2019-07-04", commandPrefix, commandPrefix), nil
+	}
+	month, day, year, err := parseDay(args[0])
+	if err != nil {
+		return fmt.Sprintf("%v.", err), nil
+	}
+	if !archive.exists(message.GroupID) {
+		return notArchivedReply, nil
+	}
+	group, err := groupMe.getGroup(message.GroupID)
+	if err != nil {
+		return "", err
+	}
+	finder, err := loadCandidateFinder(group)
+	if err != nil {
+		return "", err
+	}
+	dayName := fmt.Sprintf("%d/%d", int(month), day)
+	if year != 0 {
+		dayName += fmt.Sprintf("/%d", year)
+	}
+	popularMessages := finder.popularOn(month, day, year)
+	if len(popularMessages) == 0 {
+		return fmt.Sprintf("Nothing popular from %s.", dayName), nil
+	}
+	if len(popularMessages) > numOnMessages {
+		popularMessages = popularMessages[:numOnMessages]
+	}
+	loc := finder.groupConfig.Loc()
+	entries := []string{fmt.Sprintf("Most liked from %s:", dayName)}
+	for _, popularMessage := range popularMessages {
+		text := repostText(popularMessage, loc)
+		for _, attachment := range popularMessage.Attachments {
+			text += "\n" + attachmentFallback(attachment)
+		}
+		entries = append(entries, text)
+	}
+	return truncate(strings.Join(entries, "\n\n"), groupMeMaxMessageLength), nil
+}
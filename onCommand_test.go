@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDay(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		month time.Month
+		day   int
+		year  int
+		ok    bool
+	}{
+		{"7/4", time.July, 4, 0, true},
+		{"07-04", time.July, 4, 0, true},
+		{"7/4/19", time.July, 4, 2019, true},
+		{"7/4/2019", time.July, 4, 2019, true},
+		{"2019-07-04", time.July, 4, 2019, true},
+		{"2/29", time.February, 29, 0, true},
+		{"2/29/2019", 0, 0, 0, false},
+		{"13/1", 0, 0, 0, false},
+		{"7", 0, 0, 0, false},
+		{"july 4", 0, 0, 0, false},
+	} {
+		month, day, year, err := parseDay(test.value)
+		if month != test.month || day != test.day || year != test.year || (err == nil) != test.ok {
+			t.Errorf("parseDay(%q) = %s %d %d, %v", test.value, month, day, year, err)
+		}
+	}
+}
+
+func TestOnCommandListsTheMostLikedFirst(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	loc := appConfig.Bot.Loc()
+	fake.addMessage("g1", "a", "liked by three", time.Date(2019, 7, 4, 12, 0, 0, 0, loc), "a", "b", "c")
+	fake.addMessage("g1", "b", "liked by all", time.Date(2020, 7, 4, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	fake.addMessage("g1", "c", "not popular", time.Date(2020, 7, 4, 13, 0, 0, 0, loc))
+	fake.addMessage("g1", "d", "other day", time.Date(2020, 7, 5, 12, 0, 0, 0, loc), "a", "b", "c", "d")
+	err := archive.sync("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := dispatchCommand(Message{GroupID: "g1", Text: "!on 7/4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, three := strings.Index(reply, "liked by all"), strings.Index(reply, "liked by three")
+	if !strings.HasPrefix(reply, "Most liked from 7/4:") || all < 0 || three < all || strings.Contains(reply, "not popular") || strings.Contains(reply, "other day") {
+		t.Errorf("!on 7/4 replied %q", reply)
+	}
+	reply, _ = dispatchCommand(Message{GroupID: "g1", Text: "!on 7/4/2019"})
+	if !strings.Contains(reply, "liked by three") || strings.Contains(reply, "liked by all") {
+		t.Errorf("!on 7/4/2019 replied %q", reply)
+	}
+	reply, _ = dispatchCommand(Message{GroupID: "g1", Text: "!on 1/1"})
+	if reply != "Nothing popular from 1/1." {
+		t.Errorf("!on 1/1 replied %q", reply)
+	}
+}
+
+func TestOnCommandBeforeTheFirstSync(t *testing.T) {
+	newTestGroup(t, "g1", "a", "b")
+	reply, err := dispatchCommand(Message{GroupID: "g1", Text: "!on 7/4"})
+	if reply != notArchivedReply || err != nil {
+		t.Errorf("!on before the first sync got %q, %v", reply, err)
+	}
+}
@@ -1,4 +1,4 @@
 #!/bin/sh
 set GOOS=linux
-go build -o main main.go
+go build -o main .
 ~/Go/bin/build-lambda-zip.exe -output main.zip main
\ No newline at end of file
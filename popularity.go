@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+var defaultExcludedPhrases = []string{"like this"}
+
+//popularity decides which of a group's messages are worth reposting
+type popularity struct {
+	rules           *dbConnection.PopularityRules //nil means the default member count tiers
+	percentileLikes int                           //like count at rules.LikePercentile of the group's history
+}
+
+func newPopularity(groupID string, rules *dbConnection.PopularityRules) popularity {
+	popularity := popularity{rules: rules}
+	if rules != nil && rules.LikePercentile > 0 {
+		var likeCounts []int
+		for _, message := range archive.allMessages(groupID) {
+			if message.SenderType == "bot" || message.System {
+				continue
+			}
+			likeCounts = append(likeCounts, message.numLikes())
+		}
+		popularity.percentileLikes = percentile(likeCounts, rules.LikePercentile)
+		if popularity.percentileLikes < 1 { //in a quiet group the percentile can be 0 likes, which every message has
+			popularity.percentileLikes = 1
+		}
+	}
+	return popularity
+}
+
+//percentile returns the smallest count that is at least pct percent of counts, using nearest rank
+func percentile(counts []int, pct float32) int {
+	if len(counts) == 0 {
+		return 0
+	}
+	sort.Ints(counts)
+	rank := int(math.Ceil(float64(pct) / 100 * float64(len(counts))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(counts) {
+		rank = len(counts)
+	}
+	return counts[rank-1]
+}
+
+func (message Message) isPopular(popularity popularity) bool {
+	rules := popularity.rules
+	excludedPhrases := defaultExcludedPhrases
+	if rules != nil {
+		excludedPhrases = rules.ExcludedPhrases
+	}
+	for _, phrase := range excludedPhrases {
+		if strings.Contains(strings.ToLower(message.Text), strings.ToLower(phrase)) {
+			return false
+		}
+	}
+	if !hasThreshold(rules) {
+		return message.isPopularByDefaultTiers()
+	}
+	if rules.MinLikes > 0 && message.numLikes() < rules.MinLikes {
+		return false
+	}
+	if rules.MinLikePercentage > 0 && message.percentageLikes()*100 < rules.MinLikePercentage {
+		return false
+	}
+	if rules.LikePercentile > 0 && message.numLikes() < popularity.percentileLikes {
+		return false
+	}
+	return true
+}
+
+//hasThreshold is false for nil rules and for rules with every threshold off, which would make every message popular.
+//Those use the default tiers, keeping any excluded phrases
+func hasThreshold(rules *dbConnection.PopularityRules) bool {
+	return rules != nil && (rules.MinLikes > 0 || rules.MinLikePercentage > 0 || rules.LikePercentile > 0)
+}
+
+func (message Message) isPopularByDefaultTiers() bool {
+	if message.numMembersAtTime <= 5 && (message.numLikes() < message.numMembersAtTime-1) {
+		return false
+	} else if message.numMembersAtTime >= 17 && message.numLikes() < 8 {
+		return false
+	} else if message.numMembersAtTime > 5 && message.numMembersAtTime < 17 && message.numLikes() < (4+(message.numMembersAtTime-5)/3) {
+		return false
+	}
+	return true
+
+}
+
+func describePopularityRules(rules *dbConnection.PopularityRules) string {
+	if rules == nil {
+		return "default"
+	}
+	if !hasThreshold(rules) {
+		return fmt.Sprintf("default, excluding %q", rules.ExcludedPhrases)
+	}
+	return fmt.Sprintf("at least %d likes, %g%% of members, %gth percentile, excluding %q", rules.MinLikes, rules.MinLikePercentage, rules.LikePercentile, rules.ExcludedPhrases)
+}
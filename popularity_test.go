@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+func TestRulesWithNoThresholdsUseDefaultTiers(t *testing.T) {
+	rules := &dbConnection.PopularityRules{ExcludedPhrases: []string{"skip"}}
+	unliked := Message{Text: "hi", numMembersAtTime: 10}
+	if unliked.isPopular(popularity{rules: rules}) {
+		t.Fatal("an unliked message was popular under rules with every threshold at 0")
+	}
+	liked := Message{Text: "hi", FavoriteBy: []string{"1", "2", "3", "4", "5", "6"}, numMembersAtTime: 10}
+	if !liked.isPopular(popularity{rules: rules}) {
+		t.Fatal("a message over the default tier wasn't popular")
+	}
+	liked.Text = "skip this"
+	if liked.isPopular(popularity{rules: rules}) {
+		t.Fatal("the excluded phrase was ignored")
+	}
+}
+
+func TestLikePercentileNeedsAtLeastOneLike(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	sent := time.Date(2019, 7, 4, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		fake.addMessage("g1", "a", "quiet", sent.Add(time.Duration(i)*time.Minute))
+	}
+	fake.addMessage("g1", "b", "liked", sent.Add(time.Hour), "a")
+	err := archive.sync("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	popularity := newPopularity("g1", &dbConnection.PopularityRules{LikePercentile: 75})
+	for _, message := range archive.allMessages("g1") {
+		if popular := message.isPopular(popularity); popular != (message.Text == "liked") {
+			t.Errorf("%q with %d likes: popular is %t", message.Text, message.numLikes(), popular)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+//oldRepostFormat matches the byline postMessage puts under every repost, with the like count captured
+var oldRepostFormat = regexp.MustCompile(` \n\n- .* \| \d{1,2}/\d{1,2}/\d{2} \| ❤️x(\d*)`)
+
+//getRepostedYears maps the id of every message the bot has reposted in the group to the latest year it was reposted in loc
+func getRepostedYears(groupID string, loc *time.Location) map[string]int {
+	repostedYears := make(map[string]int)
+	for _, repost := range store.GetReposts(groupID) {
+		year := time.Unix(repost.RepostedAt, 0).In(loc).Year()
+		if year > repostedYears[repost.MessageId] {
+			repostedYears[repost.MessageId] = year
+		}
+	}
+	return repostedYears
+}
+
+//recordRepost saves that message was just reposted. If it fails the message could be picked again, so callers fail the run
+func recordRepost(groupID string, message Message) error {
+	return store.AddRepost(dbConnection.Repost{
+		GroupId:    groupID,
+		MessageId:  message.MessageID,
+		AuthorName: message.Name,
+		AuthorId:   message.SenderID,
+		SentAt:     message.TimeSent,
+		RepostedAt: time.Now().Unix(),
+		Likes:      message.numLikes(),
+	})
+}
+
+//parseOldRepost reads the quoted original text and like count out of a repost in the old format. ok is false if text isn't one
+func parseOldRepost(text string) (original string, likes int, ok bool) {
+	match := oldRepostFormat.FindStringSubmatchIndex(text)
+	if match == nil {
+		return "", 0, false
+	}
+	if match[0]-1 > 1 {
+		original = text[1 : match[0]-1]
+	}
+	likes, _ = strconv.Atoi(text[match[2]:match[3]])
+	return original, likes, true
+}
+
+//repostKey is what a message looked like once reposted: its text without the quotes plus its first attachment
+func repostKey(text string, attachments []Attachment) string {
+	if len(attachments) > 0 {
+		text += attachments[0].URL
+	}
+	return text
+}
+
+//migrateReposts backfills repost records from the bot's old posts, matching each one to the original message
+//by text and attachment the same way addMessagesFromDate used to. Reposts that are already recorded are skipped
+func migrateReposts() {
+	items, err := store.GetAllItems()
+	if err != nil {
+		log.Print("Error reached when getting the groups, nothing was migrated.")
+		log.Print(err)
+		return
+	}
+	for _, item := range items {
+		groupID := item.GroupId
+		groupConfig := groupSettings(groupID)
+		err := archive.sync(groupID)
+		if err != nil {
+			log.Print(fmt.Sprintf("Error reached when syncing group %s, skipping it.", groupID))
+			log.Print(err)
+			continue
+		}
+		messages := archive.allMessages(groupID)
+
+		originals := make(map[string][]*Message)
+		for _, message := range messages {
+			if message.SenderType == "bot" || message.System {
+				continue
+			}
+			key := repostKey(message.Text, message.Attachments)
+			originals[key] = append(originals[key], message)
+		}
+		recorded := make(map[string]bool)
+		for _, repost := range store.GetReposts(groupID) {
+			recorded[fmt.Sprintf("%s/%d", repost.MessageId, repost.RepostedAt)] = true
+		}
+
+		numMigrated := 0
+		for _, message := range messages {
+			if message.SenderType != "bot" && message.Name != groupConfig.Name {
+				continue
+			}
+			text, likes, ok := parseOldRepost(message.Text)
+			if !ok {
+				continue
+			}
+			original := findOriginal(originals[repostKey(text, message.Attachments)], message, groupConfig.Loc())
+			if original == nil {
+				log.Print(fmt.Sprintf("Couldn't find the original of repost %s in group %s.", message.MessageID, groupID))
+				continue
+			}
+			if recorded[fmt.Sprintf("%s/%d", original.MessageID, message.TimeSent)] {
+				continue
+			}
+			err := store.AddRepost(dbConnection.Repost{
+				GroupId:    groupID,
+				MessageId:  original.MessageID,
+				AuthorName: original.Name,
+				AuthorId:   original.SenderID,
+				SentAt:     original.TimeSent,
+				RepostedAt: message.TimeSent,
+				Likes:      likes,
+			})
+			if err != nil {
+				log.Print(fmt.Sprintf("Error reached when saving repost %s in group %s.", message.MessageID, groupID))
+				log.Print(err)
+				continue
+			}
+			numMigrated++
+		}
+		log.Print(fmt.Sprintf("Migrated %d reposts for group %s.", numMigrated, groupID))
+	}
+}
+
+//findOriginal picks the candidate sent on the same day as the repost in an earlier year
+func findOriginal(candidates []*Message, repost *Message, loc *time.Location) *Message {
+	repostYear, repostMonth, repostDay := time.Unix(repost.TimeSent, 0).In(loc).Date()
+	for _, candidate := range candidates {
+		year, month, day := time.Unix(candidate.TimeSent, 0).In(loc).Date()
+		if year < repostYear && month == repostMonth && day == repostDay {
+			return candidate
+		}
+	}
+	return nil
+}
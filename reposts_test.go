@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+func TestParseOldRepost(t *testing.T) {
+	for _, test := range []struct {
+		text     string
+		original string
+		likes    int
+		ok       bool
+	}{
+		{"\"hello\" \n\n- Alex | 7/4/19 | ❤️x5", "hello", 5, true},
+		{"\"two\nlines \"quoted\"\" \n\n- Sam Smith | 12/25/20 | ❤️x12", "two\nlines \"quoted\"", 12, true},
+		{" \n\n- Alex | 1/1/21 | ❤️x3", "", 3, true}, //a picture with no text
+		{"\"hello\" \n\n- Alex | 7/4/19 | ❤️x", "hello", 0, true},
+		{"\"hello\" \n\n- Alex | 7/4/2019 | ❤️x5", "", 0, false},
+		{"hello - Alex | 7/4/19 | ❤️x5", "", 0, false},
+		{"just a message", "", 0, false},
+	} {
+		original, likes, ok := parseOldRepost(test.text)
+		if original != test.original || likes != test.likes || ok != test.ok {
+			t.Errorf("parseOldRepost(%q) = %q, %d, %t, want %q, %d, %t", test.text, original, likes, ok, test.original, test.likes, test.ok)
+		}
+	}
+}
+
+func TestFindOriginal(t *testing.T) {
+	losAngeles, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(year int, month time.Month, day, hour int, loc *time.Location) *Message {
+		sent := time.Date(year, month, day, hour, 0, 0, 0, loc)
+		return &Message{MessageID: sent.Format(time.RFC3339), TimeSent: sent.Unix()}
+	}
+	repost := at(2021, 7, 4, 9, losAngeles)
+	earlierYear := at(2019, 7, 4, 12, losAngeles)
+	sameYear := at(2021, 7, 4, 8, losAngeles)
+	otherDay := at(2019, 7, 3, 12, losAngeles)
+	lateOnTheFourth := at(2019, 7, 4, 23, losAngeles) //already the 5th in UTC
+	for _, test := range []struct {
+		name       string
+		candidates []*Message
+		loc        *time.Location
+		want       *Message
+	}{
+		{"earlier year", []*Message{earlierYear}, losAngeles, earlierYear},
+		{"same year", []*Message{sameYear}, losAngeles, nil},
+		{"other day", []*Message{otherDay}, losAngeles, nil},
+		{"skips to the match", []*Message{sameYear, otherDay, lateOnTheFourth}, losAngeles, lateOnTheFourth},
+		{"in the group's zone", []*Message{lateOnTheFourth}, losAngeles, lateOnTheFourth},
+		{"not in another zone", []*Message{lateOnTheFourth}, time.UTC, nil},
+	} {
+		if got := findOriginal(test.candidates, repost, test.loc); got != test.want {
+			t.Errorf("%s: found %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+//failingRepostStore is a memory store that can't save reposts
+type failingRepostStore struct {
+	*dbConnection.MemoryStore
+	err error
+}
+
+func (store failingRepostStore) AddRepost(repost dbConnection.Repost) error {
+	return store.err
+}
+
+func TestFailedRepostWritesFailTheRun(t *testing.T) {
+	fake := newTestGroup(t, "g1", "a", "b", "c", "d")
+	fake.addMessage("g1", "a", "hello", time.Now().In(appConfig.Bot.Loc()).AddDate(-2, 0, 0), "a", "b", "c", "d")
+	writeErr := errors.New("write failed")
+	store = failingRepostStore{MemoryStore: store.(*dbConnection.MemoryStore), err: writeErr}
+
+	err := sendMessages()
+	if err == nil {
+		t.Fatal("the run succeeded without recording its repost")
+	}
+}
@@ -1,2 +1,2 @@
 #!/bin/sh
-go run main.go --local
\ No newline at end of file
+go run . --local
\ No newline at end of file
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+//groupReport is how one group's part of a run went
+type groupReport struct {
+	GroupID    string
+	Group      string
+	Candidates int
+	Posted     []string //ids of the messages posted, one per day at most
+	Err        error
+	Duration   time.Duration
+	candidates []candidateReport //only filled in on a dry run
+	preview    string            //what a dry run would have posted, when that isn't a single memory
+}
+
+//MarshalJSON writes the error as its message and the duration in seconds
+func (report groupReport) MarshalJSON() ([]byte, error) {
+	errorMessage := ""
+	if report.Err != nil {
+		errorMessage = report.Err.Error()
+	}
+	var messages []candidateReport
+	for _, candidate := range report.candidates {
+		if candidate.MessageID != "" { //skip the placeholders for dates with no candidates
+			messages = append(messages, candidate)
+		}
+	}
+	return json.Marshal(struct {
+		GroupID    string            `json:"group_id"`
+		Group      string            `json:"group"`
+		Candidates int               `json:"candidates"`
+		Posted     []string          `json:"posted,omitempty"`
+		Error      string            `json:"error,omitempty"`
+		Seconds    float64           `json:"seconds"`
+		Messages   []candidateReport `json:"messages,omitempty"`
+		Preview    string            `json:"preview,omitempty"`
+	}{report.GroupID, report.Group, report.Candidates, report.Posted, errorMessage, report.Duration.Seconds(), messages, report.preview})
+}
+
+//runReport is every group's report from one run, in the order the groups came out of the store
+type runReport struct {
+	Groups   []groupReport `json:"groups"`
+	Duration time.Duration `json:"-"`
+}
+
+func (report runReport) numFailed() int {
+	numFailed := 0
+	for _, group := range report.Groups {
+		if group.Err != nil {
+			numFailed++
+		}
+	}
+	return numFailed
+}
+
+func (report runReport) numPosted() int {
+	numPosted := 0
+	for _, group := range report.Groups {
+		numPosted += len(group.Posted)
+	}
+	return numPosted
+}
+
+//candidates is every group's dry run candidates, in group order
+func (report runReport) candidates() []candidateReport {
+	var candidates []candidateReport
+	for _, group := range report.Groups {
+		candidates = append(candidates, group.candidates...)
+	}
+	return candidates
+}
+
+//err is nil unless a group failed
+func (report runReport) err() error {
+	if numFailed := report.numFailed(); numFailed > 0 {
+		return fmt.Errorf("%d of %d groups failed", numFailed, len(report.Groups))
+	}
+	return nil
+}
+
+//log prints one line per group and then the totals
+func (report runReport) log() {
+	for _, group := range report.Groups {
+		name := group.Group
+		if name == "" {
+			name = group.GroupID
+		}
+		switch {
+		case group.Err != nil:
+			log.Print(fmt.Sprintf("Group %s failed after %s: %s", name, group.Duration.Round(time.Millisecond), group.Err))
+		case len(group.Posted) > 0:
+			log.Print(fmt.Sprintf("Group %s posted %s out of %d candidates in %s", name, strings.Join(group.Posted, ", "), group.Candidates, group.Duration.Round(time.Millisecond)))
+		default:
+			log.Print(fmt.Sprintf("Group %s had %d candidates and posted nothing in %s", name, group.Candidates, group.Duration.Round(time.Millisecond)))
+		}
+	}
+	log.Print(fmt.Sprintf("Run finished in %s: %d groups, %d messages posted, %d failed", report.Duration.Round(time.Millisecond), len(report.Groups), report.numPosted(), report.numFailed()))
+}
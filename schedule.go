@@ -0,0 +1,141 @@
+package main
+
+import (
+	"GroupMeChatBot/dbConnection"
+	"GroupMeChatBot/scheduler"
+	"fmt"
+	"log"
+	"time"
+)
+
+const cronRefreshInterval = 10 * time.Minute
+
+var jobScheduler scheduler.Scheduler
+var randomizer *scheduler.Randomizer
+
+//scheduledRun is what a scheduled Lambda invocation gets. The single CloudWatch rule sends no group id, so every group runs
+type scheduledRun struct {
+	GroupID string `json:"group_id"`
+}
+
+//newJobScheduler picks the scheduler from the config. It's nil for the none backend
+func newJobScheduler() scheduler.Scheduler {
+	switch appConfig.Schedule.Backend {
+	case "cloudwatch":
+		return scheduler.NewCloudWatchScheduler(appConfig.Schedule.TargetARN)
+	case "cron":
+		return scheduler.NewCron(func(job scheduler.Job) {
+			err := runScheduledJob(job)
+			if err != nil {
+				log.Print(fmt.Sprintf("Error reached when running job %s.", job.Name))
+				log.Print(err)
+			}
+		})
+	}
+	return nil
+}
+
+//perGroupSchedules is true when every group can get its own job. The single CloudWatch rule with no target arn can't say which group to run
+func perGroupSchedules() bool {
+	return appConfig.Schedule.Backend == "cron" || appConfig.Schedule.TargetARN != ""
+}
+
+func jobName(groupID string) string {
+	return fmt.Sprintf("%s-%s", appConfig.Schedule.RuleName, groupID)
+}
+
+//nextJobs picks the next run after after for every item's group, each in its own window, or one run of every group in the bot's window
+func nextJobs(items []dbConnection.Item, after time.Time) []scheduler.Job {
+	if !perGroupSchedules() {
+		return []scheduler.Job{{Name: appConfig.Schedule.RuleName, At: randomizer.Next(after, appConfig.Bot.ScheduleWindow())}}
+	}
+	var jobs []scheduler.Job
+	for _, item := range items {
+		window := groupSettings(item.GroupId).ScheduleWindow()
+		jobs = append(jobs, scheduler.Job{Name: jobName(item.GroupId), GroupID: item.GroupId, At: randomizer.Next(after, window)})
+	}
+	return jobs
+}
+
+//scheduleJobs schedules every job, still trying the rest when one fails
+func scheduleJobs(jobs []scheduler.Job) error {
+	if jobScheduler == nil {
+		log.Print("No scheduler, not scheduling the next run.")
+		return nil
+	}
+	numFailed := 0
+	for _, job := range jobs {
+		err := jobScheduler.Schedule(job)
+		if err != nil {
+			log.Print(fmt.Sprintf("Error reached when scheduling job %s.", job.Name))
+			log.Print(err)
+			numFailed++
+			continue
+		}
+		log.Print(fmt.Sprintf("Scheduled job %s for %s", job.Name, job.At.Format(time.RFC1123)))
+	}
+	if numFailed > 0 {
+		return fmt.Errorf("%d of %d jobs failed to schedule", numFailed, len(jobs))
+	}
+	return nil
+}
+
+//runScheduledJob runs the job's group, or every group, then schedules what it ran again.
+//The next run still gets scheduled when some groups failed
+func runScheduledJob(job scheduler.Job) error {
+	if job.GroupID == "" {
+		err := sendMessages()
+		items, scheduleErr := store.GetAllItems()
+		if scheduleErr == nil {
+			scheduleErr = scheduleJobs(nextJobs(items, time.Now()))
+		}
+		if err != nil {
+			return err
+		}
+		return scheduleErr
+	}
+	item, ok, err := store.GetItem(job.GroupID)
+	if err != nil {
+		return err
+	}
+	if !ok || item.BotId == "" {
+		log.Print(fmt.Sprintf("Group %s doesn't have the bot anymore, cancelling its job.", job.GroupID))
+		if jobScheduler == nil {
+			return nil
+		}
+		return jobScheduler.Cancel(jobName(job.GroupID))
+	}
+	report := sendMessagesForItems([]dbConnection.Item{item}, []runTime{runningAt(time.Now())})
+	scheduleErr := scheduleJobs(nextJobs([]dbConnection.Item{item}, time.Now()))
+	if err := report.err(); err != nil {
+		return err
+	}
+	return scheduleErr
+}
+
+//runCron keeps the process running jobs on the in-process scheduler, picking up groups the bot is added to as it goes
+func runCron() {
+	cron, ok := jobScheduler.(*scheduler.Cron)
+	if !ok {
+		log.Fatalln("The cron scheduler isn't set up.")
+	}
+	for {
+		var newItems []dbConnection.Item
+		items, err := store.GetAllItems()
+		if err != nil {
+			log.Print(err)
+		}
+		for _, item := range items {
+			if !cron.Has(jobName(item.GroupId)) {
+				newItems = append(newItems, item)
+			}
+		}
+		if len(newItems) > 0 {
+			err := scheduleJobs(nextJobs(newItems, time.Now()))
+			if err != nil {
+				log.Print(err)
+			}
+		}
+		time.Sleep(cronRefreshInterval)
+	}
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+)
+
+const cloudWatchTargetID = "GroupMeChatBot"
+
+//CloudWatchScheduler schedules jobs as CloudWatch Events rules named after them. A rule fires every week on the
+//job's weekday and time, so a run that fails to schedule the next one still runs again a week later.
+//With a TargetARN each rule invokes it with {"group_id": ...} as the input. Without one the rule keeps whatever
+//target it was set up with
+type CloudWatchScheduler struct {
+	cloudWatchClient *cloudwatchevents.CloudWatchEvents
+	sessionOnce      sync.Once
+	targetARN        string
+}
+
+func NewCloudWatchScheduler(targetARN string) *CloudWatchScheduler {
+	return &CloudWatchScheduler{targetARN: targetARN}
+}
+
+func (scheduler *CloudWatchScheduler) startSession() {
+	scheduler.sessionOnce.Do(func() {
+		sess := session.Must(session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		}))
+		scheduler.cloudWatchClient = cloudwatchevents.New(sess)
+	})
+}
+
+//cronExpression is a CloudWatch cron for at's UTC weekday and time. CloudWatch counts weekdays from 1 for Sunday
+func cronExpression(job Job) string {
+	at := job.At.UTC()
+	return fmt.Sprintf("cron(%d %d ? * %d *)", at.Minute(), at.Hour(), int(at.Weekday())+1)
+}
+
+func (scheduler *CloudWatchScheduler) Schedule(job Job) error {
+	scheduler.startSession()
+	nextTrigger := cloudwatchevents.PutRuleInput{}
+	nextTrigger.SetScheduleExpression(cronExpression(job))
+	nextTrigger.SetName(job.Name)
+	_, err := scheduler.cloudWatchClient.PutRule(&nextTrigger)
+	if err != nil {
+		return fmt.Errorf("putting rule %s: %w", job.Name, err)
+	}
+	if scheduler.targetARN == "" {
+		return nil
+	}
+	input, err := json.Marshal(map[string]string{"group_id": job.GroupID})
+	if err != nil {
+		return err
+	}
+	_, err = scheduler.cloudWatchClient.PutTargets(&cloudwatchevents.PutTargetsInput{
+		Rule: aws.String(job.Name),
+		Targets: []*cloudwatchevents.Target{{
+			Id:    aws.String(cloudWatchTargetID),
+			Arn:   aws.String(scheduler.targetARN),
+			Input: aws.String(string(input)),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("putting the target of rule %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+//Cancel removes the rule and its target
+func (scheduler *CloudWatchScheduler) Cancel(name string) error {
+	scheduler.startSession()
+	_, err := scheduler.cloudWatchClient.RemoveTargets(&cloudwatchevents.RemoveTargetsInput{
+		Rule: aws.String(name),
+		Ids:  []*string{aws.String(cloudWatchTargetID)},
+	})
+	if err != nil {
+		return fmt.Errorf("removing the target of rule %s: %w", name, err)
+	}
+	_, err = scheduler.cloudWatchClient.DeleteRule(&cloudwatchevents.DeleteRuleInput{Name: aws.String(name)})
+	if err != nil {
+		return fmt.Errorf("deleting rule %s: %w", name, err)
+	}
+	return nil
+}
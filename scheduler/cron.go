@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//Cron runs jobs in this process when their time comes, for self hosting without CloudWatch.
+//Jobs are forgotten when the process exits, so whoever runs it schedules them again on start
+type Cron struct {
+	mutex  sync.Mutex
+	run    func(job Job)
+	jobs   map[string]Job
+	timers map[string]*time.Timer
+}
+
+//NewCron makes a Cron that calls run, in its own goroutine, for each job when it's due
+func NewCron(run func(job Job)) *Cron {
+	return &Cron{run: run, jobs: make(map[string]Job), timers: make(map[string]*time.Timer)}
+}
+
+func (cron *Cron) Schedule(job Job) error {
+	cron.mutex.Lock()
+	defer cron.mutex.Unlock()
+	if timer, ok := cron.timers[job.Name]; ok {
+		timer.Stop()
+	}
+	cron.jobs[job.Name] = job
+	cron.timers[job.Name] = time.AfterFunc(time.Until(job.At), func() {
+		cron.mutex.Lock()
+		current, ok := cron.jobs[job.Name]
+		if ok && current == job { //it wasn't replaced or cancelled while the timer was firing
+			delete(cron.jobs, job.Name)
+			delete(cron.timers, job.Name)
+		}
+		cron.mutex.Unlock()
+		if ok && current == job {
+			cron.run(job)
+		}
+	})
+	return nil
+}
+
+func (cron *Cron) Cancel(name string) error {
+	cron.mutex.Lock()
+	defer cron.mutex.Unlock()
+	if timer, ok := cron.timers[name]; ok {
+		timer.Stop()
+	}
+	delete(cron.jobs, name)
+	delete(cron.timers, name)
+	return nil
+}
+
+//Jobs is every job waiting to run, soonest first
+func (cron *Cron) Jobs() []Job {
+	cron.mutex.Lock()
+	defer cron.mutex.Unlock()
+	var jobs []Job
+	for _, job := range cron.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].At.Before(jobs[j].At)
+	})
+	return jobs
+}
+
+//Has is true if a job named name is waiting to run
+func (cron *Cron) Has(name string) bool {
+	cron.mutex.Lock()
+	defer cron.mutex.Unlock()
+	_, ok := cron.jobs[name]
+	return ok
+}
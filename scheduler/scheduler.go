@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//Job is one future run of the bot. GroupID is empty for a run of every group
+type Job struct {
+	Name    string
+	GroupID string
+	At      time.Time
+}
+
+//Scheduler arranges for jobs to run. Scheduling a job with the same name as an earlier one replaces it
+type Scheduler interface {
+	Schedule(job Job) error
+	Cancel(name string) error
+}
+
+//Window is the part of every day, in Location, that a group's memory can be posted in
+type Window struct {
+	Start    int //minutes after midnight
+	End      int
+	Location *time.Location
+}
+
+//ParseWindow reads a window like "08:00-18:00" in loc
+func ParseWindow(value string, loc *time.Location) (Window, error) {
+	var startHour, startMinute, endHour, endMinute int
+	_, err := fmt.Sscanf(value, "%d:%d-%d:%d", &startHour, &startMinute, &endHour, &endMinute)
+	if err != nil {
+		return Window{}, fmt.Errorf("window %q isn't HH:MM-HH:MM", value)
+	}
+	window := Window{Start: startHour*60 + startMinute, End: endHour*60 + endMinute, Location: loc}
+	if startMinute >= 60 || endMinute >= 60 || window.Start < 0 || window.End > 24*60 || window.Start >= window.End {
+		return Window{}, fmt.Errorf("window %q has to start before it ends on the same day", value)
+	}
+	return window, nil
+}
+
+func (window Window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d %s", window.Start/60, window.Start%60, window.End/60, window.End%60, window.Location)
+}
+
+//Randomizer picks fire times inside windows. The same seed always picks the same times
+type Randomizer struct {
+	mutex sync.Mutex
+	rng   *rand.Rand
+}
+
+//NewRandomizer makes a Randomizer from seed, or from the clock if seed is 0
+func NewRandomizer(seed int64) *Randomizer {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Randomizer{rng: rand.New(rand.NewSource(seed))}
+}
+
+//Next picks a random minute in the first window that starts after after: today's if it hasn't started yet, otherwise tomorrow's
+func (randomizer *Randomizer) Next(after time.Time, window Window) time.Time {
+	randomizer.mutex.Lock()
+	offset := randomizer.rng.Intn(window.End - window.Start)
+	randomizer.mutex.Unlock()
+	local := after.In(window.Location)
+	year, month, day := local.Date()
+	if !time.Date(year, month, day, 0, window.Start, 0, 0, window.Location).After(after) {
+		day++
+	}
+	return time.Date(year, month, day, 0, window.Start+offset, 0, 0, window.Location)
+}
@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeededRandomizerPicksTheSameTimes(t *testing.T) {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip(err)
+	}
+	window, err := ParseWindow("08:00-18:00", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := NewRandomizer(42), NewRandomizer(42)
+	after := time.Date(2021, 3, 13, 20, 0, 0, 0, loc) //the night before daylight saving starts
+	for i := 0; i < 5; i++ {
+		a, b := first.Next(after, window), second.Next(after, window)
+		if !a.Equal(b) {
+			t.Fatalf("run %d fired at %s and %s with the same seed", i, a, b)
+		}
+		if !a.After(after) || a.Hour() < 8 || a.Hour() >= 18 || a.Day() != 14 {
+			t.Fatalf("run %d fired at %s, want March 14 between 08:00 and 18:00", i, a)
+		}
+	}
+}
+
+func TestNextIsTodayBeforeTheWindowStarts(t *testing.T) {
+	window, err := ParseWindow("08:00-09:00", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := NewRandomizer(1).Next(time.Date(2021, 3, 14, 7, 0, 0, 0, time.UTC), window)
+	if next.Day() != 14 || next.Hour() != 8 {
+		t.Fatalf("fired at %s, want March 14 in the 8 o'clock hour", next)
+	}
+}
+
+func TestParseWindowRejectsBadWindows(t *testing.T) {
+	for _, value := range []string{"18:00-08:00", "8-9", "08:70-09:00", "09:00-09:00"} {
+		if _, err := ParseWindow(value, time.UTC); err == nil {
+			t.Errorf("%q parsed", value)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const numSearchResults = 5 //how many matches !search lists
+
+//searchFields are the prefixes a search term can use to match something other than the text
+var searchFields = []string{"from", "year", "month", "date", "type"}
+
+//searchIndex is an inverted index of a group's archived messages. Words in the text are indexed as they are, and
+//everything else under a field prefix: from:<word in the sender's name>, year:2019, month:2019-07, date:2019-07-04
+//and type:<attachment type>
+type searchIndex struct {
+	messages []Message
+	postings map[string]map[int][]int //term to message index to the term's positions in the message, in order
+}
+
+//searchTerm is words that have to be next to each other in order, usually just one
+type searchTerm []string
+
+var searchIndexes = struct {
+	sync.Mutex
+	byGroup map[string]cachedSearchIndex
+}{byGroup: make(map[string]cachedSearchIndex)}
+
+type cachedSearchIndex struct {
+	index   *searchIndex
+	loc     *time.Location
+	version string //the archive's version it was built from
+}
+
+//groupSearchIndex is the index of the group's archive, built again only when the archive or the group's time zone has changed
+func groupSearchIndex(groupID string) *searchIndex {
+	loc := groupSettings(groupID).Loc()
+	version := archive.version(groupID)
+	searchIndexes.Lock()
+	defer searchIndexes.Unlock()
+	cached, ok := searchIndexes.byGroup[groupID]
+	if ok && cached.version == version && cached.loc.String() == loc.String() {
+		return cached.index
+	}
+	index := newSearchIndex(archive.allMessages(groupID), loc)
+	searchIndexes.byGroup[groupID] = cachedSearchIndex{index: index, loc: loc, version: version}
+	return index
+}
+
+func newSearchIndex(messages []*Message, loc *time.Location) *searchIndex {
+	index := &searchIndex{postings: make(map[string]map[int][]int)}
+	for _, message := range messages {
+		if strings.Contains(message.Event.Type, "bot") || message.SenderType == "bot" || message.System {
+			continue
+		}
+		i := len(index.messages)
+		index.messages = append(index.messages, *message)
+		sent := time.Unix(message.TimeSent, 0).In(loc)
+		var fields []string
+		for _, word := range searchWords(message.Name) {
+			fields = append(fields, "from:"+word)
+		}
+		index.add(i, searchWords(message.Text))
+		index.add(i, fields)
+		fields = []string{"year:" + sent.Format("2006"), "month:" + sent.Format("2006-01"), "date:" + sent.Format("2006-01-02")}
+		for _, attachment := range message.Attachments {
+			fields = append(fields, "type:"+strings.ToLower(attachment.Type))
+		}
+		index.add(i, fields)
+	}
+	return index
+}
+
+//add indexes terms for message i at their positions in terms. The text and the name are added separately, so a
+//phrase can't run from one into the other
+func (index *searchIndex) add(i int, terms []string) {
+	for position, term := range terms {
+		if index.postings[term] == nil {
+			index.postings[term] = make(map[int][]int)
+		}
+		index.postings[term][i] = append(index.postings[term][i], position)
+	}
+}
+
+//searchWords splits text into lowercase words of letters and digits
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+//parseSearchQuery turns what was typed into index terms. Field terms are kept whole, and anything else is a phrase
+//of its words, so "road trip" in quotes only matches the two words together
+func parseSearchQuery(args []string) []searchTerm {
+	var terms []searchTerm
+	for _, arg := range args {
+		field := strings.SplitN(strings.ToLower(arg), ":", 2)
+		if len(field) == 2 && containsString(searchFields, field[0]) && field[1] != "" {
+			if field[0] == "from" {
+				for _, word := range searchWords(field[1]) {
+					terms = append(terms, searchTerm{"from:" + word})
+				}
+				continue
+			}
+			terms = append(terms, searchTerm{field[0] + ":" + field[1]})
+			continue
+		}
+		if words := searchWords(arg); len(words) > 0 {
+			terms = append(terms, searchTerm(words))
+		}
+	}
+	return terms
+}
+
+func containsString(values []string, value string) bool {
+	for _, known := range values {
+		if known == value {
+			return true
+		}
+	}
+	return false
+}
+
+//searchResult is a message that matched every term and its score
+type searchResult struct {
+	Message Message
+	Score   float64
+}
+
+//search finds the messages matching every term, ranked by tf-idf and then scaled up by their likes so the
+//memorable ones come first. Plain words and phrases also match the sender's name, at half weight
+func (index *searchIndex) search(terms []searchTerm, limit int) []searchResult {
+	if len(terms) == 0 {
+		return nil
+	}
+	scores := make(map[int]float64)
+	for n, term := range terms {
+		matches := make(map[int]float64)
+		index.score(term, 1, matches)
+		if !strings.Contains(term[0], ":") {
+			var name searchTerm
+			for _, word := range term {
+				name = append(name, "from:"+word)
+			}
+			index.score(name, 0.5, matches)
+		}
+		for i := range scores {
+			if _, ok := matches[i]; !ok {
+				delete(scores, i)
+			}
+		}
+		for i, score := range matches {
+			if _, ok := scores[i]; ok || n == 0 {
+				scores[i] += score
+			}
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+	results := []searchResult{}
+	for i, score := range scores {
+		message := index.messages[i]
+		results = append(results, searchResult{Message: message, Score: score * (1 + math.Log1p(float64(message.numLikes())))})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Message.TimeSent > results[j].Message.TimeSent
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+//score adds each message's tf-idf for term, times weight, to matches. A phrase's idf is the sum of its words'
+func (index *searchIndex) score(term searchTerm, weight float64, matches map[int]float64) {
+	counts := index.occurrences(term)
+	if len(counts) == 0 {
+		return
+	}
+	idf := 0.0
+	for _, word := range term {
+		idf += math.Log(1 + float64(len(index.messages))/float64(len(index.postings[word])))
+	}
+	for i, count := range counts {
+		matches[i] += weight * (1 + math.Log(float64(count))) * idf
+	}
+}
+
+//occurrences is how many times the term's words are in each message one after another
+func (index *searchIndex) occurrences(term searchTerm) map[int]int {
+	counts := make(map[int]int)
+	for i, starts := range index.postings[term[0]] {
+		for _, start := range starts {
+			found := true
+			for offset, word := range term[1:] {
+				positions := index.postings[word][i]
+				at := sort.SearchInts(positions, start+offset+1)
+				if at == len(positions) || positions[at] != start+offset+1 {
+					found = false
+					break
+				}
+			}
+			if found {
+				counts[i]++
+			}
+		}
+	}
+	return counts
+}
+
+//searchArchive searches the group's archived messages as they are, without syncing
+func searchArchive(groupID string, args []string, limit int) ([]searchResult, error) {
+	if !archive.exists(groupID) {
+		return nil, errArchiveMissing
+	}
+	return groupSearchIndex(groupID).search(parseSearchQuery(args), limit), nil
+}
+
+func init() {
+	registerCommand(Command{Name: "search", Usage: "<terms>", Help: "finds old messages. Narrow it with from:name, year:2019, month:2019-07, date:2019-07-04 or type:image", Handler: searchCommand})
+}
+
+func searchCommand(message Message, args []string) (string, error) {
+	if len(parseSearchQuery(args)) == 0 {
+		return fmt.Sprintf("Give something to search for, like %ssearch \"road trip\" from:alex", commandPrefix), nil
+	}
+	results, err := searchArchive(message.GroupID, args, numSearchResults)
+	if errors.Is(err, errArchiveMissing) {
+		return notArchivedReply, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("Nothing matched %s.", strings.Join(args, " ")), nil
+	}
+	loc := groupSettings(message.GroupID).Loc()
+	entries := []string{fmt.Sprintf("Top matches for %s:", strings.Join(args, " "))}
+	for _, result := range results {
+		text := repostText(result.Message, loc)
+		for _, attachment := range result.Message.Attachments {
+			text += "\n" + attachmentFallback(attachment)
+		}
+		entries = append(entries, text)
+	}
+	return truncate(strings.Join(entries, "\n\n"), groupMeMaxMessageLength), nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotedWordsMatchAsAPhrase(t *testing.T) {
+	messages := []*Message{
+		{MessageID: "1", Name: "Alex", Text: "best road trip ever", TimeSent: 1},
+		{MessageID: "2", Name: "Sam", Text: "trip to the road", TimeSent: 2},
+		{MessageID: "3", Name: "Road Trip", Text: "hello", TimeSent: 3},
+	}
+	index := newSearchIndex(messages, time.UTC)
+
+	results := index.search(parseSearchQuery([]string{"road trip"}), 0)
+	if len(results) != 2 || results[0].Message.MessageID != "1" || results[1].Message.MessageID != "3" {
+		t.Fatalf("\"road trip\" matched %v, want messages 1 and then 3", results)
+	}
+	results = index.search(parseSearchQuery([]string{"road", "trip"}), 0)
+	if len(results) != 3 {
+		t.Fatalf("road trip matched %d messages, want all 3", len(results))
+	}
+}
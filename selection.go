@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+const defaultTopN = 3
+
+var selectionStrategies = []string{"lottery", "top", "years", "authors"}
+
+//selection picks which popular message gets posted, as a draw weighted by the group's strategy
+type selection struct {
+	rules   dbConnection.SelectionRules
+	loc     *time.Location //the group's, for telling years apart
+	authors *authorHistory
+	now     time.Time
+	mutex   *sync.Mutex
+	rng     *rand.Rand
+}
+
+//newSelection makes the group's selection. reposts is the group's repost history, which the author penalty and cooldown go by
+func newSelection(rules *dbConnection.SelectionRules, loc *time.Location, reposts []dbConnection.Repost) selection {
+	selection := selection{loc: loc, now: time.Now(), mutex: &sync.Mutex{}}
+	if rules != nil {
+		selection.rules = *rules
+	}
+	selection.authors = newAuthorHistory(selection.rules, reposts, selection.now)
+	seed := selection.rules.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	selection.rng = rand.New(rand.NewSource(seed))
+	return selection
+}
+
+func (selection selection) float64() float64 {
+	selection.mutex.Lock()
+	defer selection.mutex.Unlock()
+	return selection.rng.Float64()
+}
+
+//weights is each message's relative chance of being picked, in the same order
+func (selection selection) weights(messages []Message) []float64 {
+	weights := make([]float64, len(messages))
+	switch selection.rules.Strategy {
+	case "top":
+		topN := selection.rules.TopN
+		if topN < 1 {
+			topN = defaultTopN
+		}
+		for rank, i := range rankByLikes(messages) {
+			if rank < topN {
+				weights[i] = 1
+			}
+		}
+	case "years":
+		selection.balance(messages, weights, func(message Message) string {
+			return fmt.Sprintf("%d", time.Unix(message.TimeSent, 0).In(selection.loc).Year())
+		})
+	case "authors":
+		selection.balance(messages, weights, authorKey)
+	default:
+		for i, message := range messages {
+			weights[i] = lotteryWeight(message)
+		}
+	}
+	selection.mutex.Lock()
+	selection.authors.penalize(messages, weights, selection.rules.AuthorPenalty, selection.now)
+	selection.mutex.Unlock()
+	return weights
+}
+
+//featured counts a message that was just posted against its author, so later picks in the same run see it
+func (selection selection) featured(message Message) {
+	selection.mutex.Lock()
+	defer selection.mutex.Unlock()
+	selection.authors.add(authorKey(message), selection.now, selection.now)
+}
+
+//balance gives every key the same total chance, shared out between its messages by the lottery
+func (selection selection) balance(messages []Message, weights []float64, key func(Message) string) {
+	totals := make(map[string]float64)
+	for _, message := range messages {
+		totals[key(message)] += lotteryWeight(message)
+	}
+	for i, message := range messages {
+		if total := totals[key(message)]; total > 0 {
+			weights[i] = lotteryWeight(message) / total
+		}
+	}
+}
+
+func lotteryWeight(message Message) float64 {
+	if message.numMembersAtTime == 0 {
+		return 0
+	}
+	return float64(message.percentageLikes())
+}
+
+//rankByLikes is the indexes of messages from most to least liked, earlier messages first on a tie
+func rankByLikes(messages []Message) []int {
+	ranked := make([]int, len(messages))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return messages[ranked[i]].numLikes() > messages[ranked[j]].numLikes()
+	})
+	return ranked
+}
+
+func validSelectionStrategy(strategy string) bool {
+	for _, known := range selectionStrategies {
+		if strategy == known {
+			return true
+		}
+	}
+	return false
+}
+
+func describeSelectionRules(rules *dbConnection.SelectionRules) string {
+	if rules == nil {
+		return "lottery"
+	}
+	description := rules.Strategy
+	if description == "" {
+		description = "lottery"
+	}
+	if rules.Strategy == "top" {
+		topN := rules.TopN
+		if topN < 1 {
+			topN = defaultTopN
+		}
+		description = fmt.Sprintf("top %d", topN)
+	}
+	if rules.Seed != 0 {
+		description += fmt.Sprintf(", seed %d", rules.Seed)
+	}
+	if rules.Count > 1 {
+		description += fmt.Sprintf(", %d memories a run", rules.Count)
+	}
+	if rules.Fallback != "" && rules.Fallback != "none" {
+		description += fmt.Sprintf(", %s fallback", rules.Fallback)
+	}
+	return description + describeAuthorRules(*rules)
+}
+
+//setSelectionRules saves the group's selection rules, or goes back to the lottery when rules is nil
+func setSelectionRules(groupID string, rules *dbConnection.SelectionRules) error {
+	item, ok, err := store.GetItem(groupID)
+	if err != nil {
+		return err
+	}
+	if !ok || item.BotId == "" {
+		return errNoBotInGroup
+	}
+	if rules != nil && !validSelectionStrategy(rules.Strategy) {
+		return fmt.Errorf("unknown strategy %q, use one of %s", rules.Strategy, strings.Join(selectionStrategies, ", "))
+	}
+	if rules != nil && !validMemoryFallback(rules.Fallback) {
+		return fmt.Errorf("unknown fallback %q, use one of %s", rules.Fallback, strings.Join(memoryFallbacks, ", "))
+	}
+	if rules != nil && rules.Count < 0 {
+		return errors.New("count can't be negative")
+	}
+	if rules != nil && (rules.AuthorPenalty < 0 || rules.AuthorPenalty > 1) {
+		return fmt.Errorf("author penalty %g must be between 0 and 1", rules.AuthorPenalty)
+	}
+	if rules != nil && (rules.AuthorPenaltyDays < 0 || rules.AuthorCooldownDays < 0) {
+		return errors.New("author penalty and cooldown days can't be negative")
+	}
+	item.SelectionRules = rules
+	return store.SaveItem(item)
+}
+
+func selectionRulesMenu(groups []Group) {
+	fmt.Println("\n\nHere are all the groups you are a member of. Enter the number corresponding to the group you want to choose the selection strategy of: ")
+	groupIndex := menuHelper(groups)
+	if groupIndex < 0 {
+		return
+	}
+	groupID := groups[groupIndex].GroupID
+	item, ok, err := store.GetItem(groupID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if !ok {
+		fmt.Println("That group doesn't have this bot.")
+		return
+	}
+	rules := dbConnection.SelectionRules{Strategy: "lottery"}
+	if item.SelectionRules != nil {
+		rules = *item.SelectionRules
+	}
+	fmt.Println(fmt.Sprintf("Current strategy: %s", describeSelectionRules(item.SelectionRules)))
+	fmt.Println("Leave any answer blank to keep its current value.")
+	if answer := promptMenu(fmt.Sprintf("Strategy, one of %s [%s]:", strings.Join(selectionStrategies, ", "), rules.Strategy)); answer != "" {
+		rules.Strategy = answer
+	}
+	if rules.Strategy == "top" {
+		if answer := promptMenu(fmt.Sprintf("Pick between how many of the most liked [%d]:", rules.TopN)); answer != "" {
+			if value, err := strconv.Atoi(answer); err == nil {
+				rules.TopN = value
+			} else {
+				fmt.Println(err)
+			}
+		}
+	}
+	if answer := promptMenu(fmt.Sprintf("Seed, for the same pick every time, or 0 for random [%d]:", rules.Seed)); answer != "" {
+		if value, err := strconv.ParseInt(answer, 10, 64); err == nil {
+			rules.Seed = value
+		} else {
+			fmt.Println(err)
+		}
+	}
+	if answer := promptMenu(fmt.Sprintf("How many memories to post each run [%d]:", rules.Count)); answer != "" {
+		if value, err := strconv.Atoi(answer); err == nil {
+			rules.Count = value
+		} else {
+			fmt.Println(err)
+		}
+	}
+	if answer := promptMenu(fmt.Sprintf("When nothing qualifies, one of %s [%s]:", strings.Join(memoryFallbacks, ", "), rules.Fallback)); answer != "" {
+		rules.Fallback = answer
+	}
+	if answer := promptMenu(fmt.Sprintf("How much less likely an author gets for each repost of theirs lately, 0-1 [%g]:", rules.AuthorPenalty)); answer != "" {
+		if value, err := strconv.ParseFloat(answer, 64); err == nil {
+			rules.AuthorPenalty = value
+		} else {
+			fmt.Println(err)
+		}
+	}
+	if rules.AuthorPenalty > 0 {
+		if answer := promptMenu(fmt.Sprintf("How many days a repost counts against its author [%d]:", rules.AuthorPenaltyDays)); answer != "" {
+			if value, err := strconv.Atoi(answer); err == nil {
+				rules.AuthorPenaltyDays = value
+			} else {
+				fmt.Println(err)
+			}
+		}
+	}
+	if answer := promptMenu(fmt.Sprintf("Days before an author can be picked again, or 0 for no cooldown [%d]:", rules.AuthorCooldownDays)); answer != "" {
+		if value, err := strconv.Atoi(answer); err == nil {
+			rules.AuthorCooldownDays = value
+		} else {
+			fmt.Println(err)
+		}
+	}
+	err = setSelectionRules(groupID, &rules)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(fmt.Sprintf("Saved strategy: %s", describeSelectionRules(&rules)))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"GroupMeChatBot/dbConnection"
+)
+
+func selectionCandidates() []Message {
+	var messages []Message
+	for i, likes := range []int{1, 2, 3, 4, 5, 6} {
+		messages = append(messages, Message{
+			MessageID:        string(rune('a' + i)),
+			SenderID:         string(rune('a' + i)),
+			TimeSent:         time.Date(2015+i, 3, 4, 12, 0, 0, 0, time.UTC).Unix(),
+			FavoriteBy:       make([]string, likes),
+			numMembersAtTime: 6,
+		})
+	}
+	return messages
+}
+
+func TestSeededSelectionPicksTheSame(t *testing.T) {
+	for _, strategy := range []string{"lottery", "top", "years", "authors"} {
+		rules := &dbConnection.SelectionRules{Strategy: strategy, Seed: 42}
+		first := newSelection(rules, time.UTC, nil)
+		second := newSelection(rules, time.UTC, nil)
+		for i := 0; i < 10; i++ {
+			candidates := selectionCandidates()
+			reversed := selectionCandidates()
+			for l, r := 0, len(reversed)-1; l < r; l, r = l+1, r-1 {
+				reversed[l], reversed[r] = reversed[r], reversed[l]
+			}
+			a := getMessageToPost(first, &candidates)
+			b := getMessageToPost(second, &reversed)
+			if a.MessageID != b.MessageID {
+				t.Fatalf("%s pick %d was %s and then %s with the same seed", strategy, i, a.MessageID, b.MessageID)
+			}
+		}
+	}
+}
+
+func TestTopOnePicksTheMostLiked(t *testing.T) {
+	selection := newSelection(&dbConnection.SelectionRules{Strategy: "top", TopN: 1, Seed: 7}, time.UTC, nil)
+	for i := 0; i < 5; i++ {
+		candidates := selectionCandidates()
+		if picked := getMessageToPost(selection, &candidates); picked.MessageID != "f" {
+			t.Fatalf("picked %s, want the most liked, f", picked.MessageID)
+		}
+	}
+}
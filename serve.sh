@@ -0,0 +1,2 @@
+#!/bin/sh
+go run . --serve
\ No newline at end of file
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const numStatsLeaders = 3 //how many members, hours and days !stats names
+
+//statsMessage is a message as shown in stats
+type statsMessage struct {
+	MessageID string `json:"message_id"`
+	Name      string `json:"name"`
+	Text      string `json:"text"`
+	Date      string `json:"date"`
+	Likes     int    `json:"likes"`
+}
+
+//memberStats is one member's messages and likes
+type memberStats struct {
+	Name          string        `json:"name"`
+	UserID        string        `json:"user_id,omitempty"`
+	Messages      int           `json:"messages"`
+	LikesReceived int           `json:"likes_received"`
+	LikesGiven    int           `json:"likes_given"`
+	TopMessage    *statsMessage `json:"top_message,omitempty"`
+}
+
+//groupStats is what a group's archived messages add up to between From and To, or for all time when they're empty
+type groupStats struct {
+	From              string         `json:"from,omitempty"`
+	To                string         `json:"to,omitempty"`
+	Messages          int            `json:"messages"`
+	Likes             int            `json:"likes"`
+	Members           []memberStats  `json:"members"` //most likes received first
+	MessagesByHour    [24]int        `json:"messages_by_hour"`
+	LikesByHour       [24]int        `json:"likes_by_hour"`
+	MessagesByWeekday map[string]int `json:"messages_by_weekday"`
+	LikesByWeekday    map[string]int `json:"likes_by_weekday"`
+	TopMessage        *statsMessage  `json:"top_message,omitempty"`
+}
+
+//computeGroupStats adds up messages sent from from until to, in loc, where a zero time leaves that end open.
+//names are the members' nicknames by user id, for people who liked things but never posted in the range
+func computeGroupStats(messages []*Message, names map[string]string, loc *time.Location, from, to time.Time) groupStats {
+	stats := groupStats{MessagesByWeekday: make(map[string]int), LikesByWeekday: make(map[string]int)}
+	if !from.IsZero() {
+		stats.From = from.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		stats.To = to.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		stats.MessagesByWeekday[day.String()] = 0
+		stats.LikesByWeekday[day.String()] = 0
+	}
+	byMember := make(map[string]*memberStats)
+	member := func(key, name string) *memberStats {
+		if _, ok := byMember[key]; !ok {
+			byMember[key] = &memberStats{Name: name, UserID: key}
+		}
+		return byMember[key]
+	}
+	for userID, name := range names {
+		member(userID, name)
+	}
+	for _, message := range messages { //newest first, so each member keeps their latest name
+		sent := time.Unix(message.TimeSent, 0).In(loc)
+		if (!from.IsZero() && sent.Before(from)) || (!to.IsZero() && !sent.Before(to)) {
+			continue
+		}
+		if strings.Contains(message.Event.Type, "bot") || message.SenderType == "bot" || message.System {
+			continue
+		}
+		likes := message.numLikes()
+		stats.Messages++
+		stats.Likes += likes
+		stats.MessagesByHour[sent.Hour()]++
+		stats.LikesByHour[sent.Hour()] += likes
+		stats.MessagesByWeekday[sent.Weekday().String()]++
+		stats.LikesByWeekday[sent.Weekday().String()] += likes
+
+		sender := member(authorKey(*message), message.Name)
+		if sender.Name == "" {
+			sender.Name = message.Name
+		}
+		sender.Messages++
+		sender.LikesReceived += likes
+		shown := &statsMessage{MessageID: message.MessageID, Name: message.Name, Text: message.Text, Date: sent.Format("2006-01-02"), Likes: likes}
+		if shown.Text == "" && len(message.Attachments) > 0 {
+			shown.Text = attachmentFallback(message.Attachments[0])
+		}
+		if likes > 0 && (sender.TopMessage == nil || likes >= sender.TopMessage.Likes) { //ties go to the older message
+			sender.TopMessage = shown
+		}
+		if likes > 0 && (stats.TopMessage == nil || likes >= stats.TopMessage.Likes) {
+			stats.TopMessage = shown
+		}
+		for _, likerID := range message.FavoriteBy {
+			member(likerID, "").LikesGiven++
+		}
+	}
+	stats.Members = []memberStats{}
+	for _, counts := range byMember {
+		if counts.Name == "" {
+			counts.Name = counts.UserID //liked something but never posted in the range and isn't a member now
+		}
+		stats.Members = append(stats.Members, *counts)
+	}
+	sort.Slice(stats.Members, func(i, j int) bool {
+		if stats.Members[i].LikesReceived != stats.Members[j].LikesReceived {
+			return stats.Members[i].LikesReceived > stats.Members[j].LikesReceived
+		}
+		return stats.Members[i].Name < stats.Members[j].Name
+	})
+	return stats
+}
+
+//memberNames is the group's current members' nicknames by user id
+func memberNames(group Group) map[string]string {
+	names := make(map[string]string)
+	for _, member := range group.Members {
+		fields, ok := member.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := fields["nickname"].(string)
+		id, _ := fields["user_id"].(string)
+		if id != "" {
+			names[id] = name
+		}
+	}
+	return names
+}
+
+//statsForGroup adds up the group's archive as it is between from and to. names are the members' nicknames by
+//user id, and without them people who never posted show up by user id
+func statsForGroup(groupID string, from, to time.Time, names map[string]string) (groupStats, error) {
+	if !archive.exists(groupID) {
+		return groupStats{}, errArchiveMissing
+	}
+	return computeGroupStats(archive.allMessages(groupID), names, groupSettings(groupID).Loc(), from, to), nil
+}
+
+//busiest is the keys of counts with the most, most first, leaving out any with none
+func busiest(counts map[string]int, limit int) []string {
+	var keys []string
+	for key, count := range counts {
+		if count > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+func (stats groupStats) hourCounts() map[string]int {
+	counts := make(map[string]int)
+	for hour, likes := range stats.LikesByHour {
+		counts[time.Date(2000, 1, 1, hour, 0, 0, 0, time.UTC).Format("3pm")] = likes
+	}
+	return counts
+}
+
+func (stats groupStats) describeRange() string {
+	switch {
+	case stats.From != "" && stats.To != "":
+		return fmt.Sprintf("%s to %s", stats.From, stats.To)
+	case stats.From != "":
+		return fmt.Sprintf("since %s", stats.From)
+	case stats.To != "":
+		return fmt.Sprintf("through %s", stats.To)
+	}
+	return "all time"
+}
+
+//formatStats is a short leaderboard that fits in one GroupMe message
+func formatStats(stats groupStats) string {
+	lines := []string{fmt.Sprintf("Stats for %s: %d messages, ❤️x%d", stats.describeRange(), stats.Messages, stats.Likes)}
+	if stats.Messages == 0 {
+		return lines[0]
+	}
+	members := append([]memberStats(nil), stats.Members...)
+	leaders := func(title, format string, count func(memberStats) int) {
+		sort.SliceStable(members, func(i, j int) bool { return count(members[i]) > count(members[j]) })
+		var names []string
+		for i := 0; i < len(members) && i < numStatsLeaders && count(members[i]) > 0; i++ {
+			names = append(names, fmt.Sprintf("%s "+format, members[i].Name, count(members[i])))
+		}
+		if len(names) > 0 {
+			lines = append(lines, fmt.Sprintf("%s: %s", title, strings.Join(names, ", ")))
+		}
+	}
+	leaders("Most liked", "❤️x%d", func(member memberStats) int { return member.LikesReceived })
+	leaders("Most generous", "%d given", func(member memberStats) int { return member.LikesGiven })
+	leaders("Most messages", "%d", func(member memberStats) int { return member.Messages })
+	lines = append(lines, fmt.Sprintf("Best hours: %s", strings.Join(busiest(stats.hourCounts(), numStatsLeaders), ", ")))
+	lines = append(lines, fmt.Sprintf("Best days: %s", strings.Join(busiest(stats.LikesByWeekday, numStatsLeaders), ", ")))
+	if stats.TopMessage != nil {
+		lines = append(lines, fmt.Sprintf("Top message: \"%s\" - %s, %s ❤️x%d", truncate(stats.TopMessage.Text, digestTextLength), stats.TopMessage.Name, stats.TopMessage.Date, stats.TopMessage.Likes))
+	}
+	return truncate(strings.Join(lines, "\n"), groupMeMaxMessageLength)
+}
+
+func init() {
+	registerCommand(Command{Name: "stats", Usage: "[year | from to]", Help: "shows who gets and gives the most likes and when the group is best, for all time, a year like 2019 or dates like 2019-01-01 2019-06-30", Handler: statsCommand})
+}
+
+func statsCommand(message Message, args []string) (string, error) {
+	loc := groupSettings(message.GroupID).Loc()
+	var from, to time.Time
+	switch len(args) {
+	case 0:
+	case 1:
+		period, err := parseMemoryPeriod(args)
+		if err != nil || period.Year == 0 {
+			return fmt.Sprintf("Give a year like %sstats 2019, or dates like %sstats 2019-01-01 2019-06-30", commandPrefix, commandPrefix), nil
+		}
+		from = time.Date(period.Year, time.January, 1, 0, 0, 0, 0, loc)
+		to = from.AddDate(1, 0, 0)
+		if period.Month != 0 {
+			from = time.Date(period.Year, period.Month, 1, 0, 0, 0, 0, loc)
+			to = from.AddDate(0, 1, 0)
+		}
+	case 2:
+		var err error
+		from, to, err = parseStatsRange(args[0], args[1], loc)
+		if err != nil {
+			return fmt.Sprintf("%v.", err), nil
+		}
+	default:
+		return fmt.Sprintf("Give a year like %sstats 2019, or dates like %sstats 2019-01-01 2019-06-30", commandPrefix, commandPrefix), nil
+	}
+	group, err := groupMe.getGroup(message.GroupID)
+	if err != nil {
+		return "", err
+	}
+	stats, err := statsForGroup(message.GroupID, from, to, memberNames(group))
+	if errors.Is(err, errArchiveMissing) {
+		return notArchivedReply, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return formatStats(stats), nil
+}
+
+//parseStatsRange reads the YYYY-MM-DD dates from and to, either of which can be empty, in loc. to is returned as the
+//start of the day after, so the range includes it
+func parseStatsRange(fromValue, toValue string, loc *time.Location) (time.Time, time.Time, error) {
+	var from, to time.Time
+	if fromValue != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", fromValue, loc)
+		if err != nil {
+			return from, to, fmt.Errorf("%q isn't a date like 2019-01-01", fromValue)
+		}
+		from = parsed
+	}
+	if toValue != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", toValue, loc)
+		if err != nil {
+			return from, to, fmt.Errorf("%q isn't a date like 2019-06-30", toValue)
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, fmt.Errorf("%s is after %s", fromValue, toValue)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComputeGroupStats(t *testing.T) {
+	sent := func(year int, month time.Month, day, hour int) int64 {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC).Unix()
+	}
+	messages := []*Message{ //newest first, like the archive
+		{MessageID: "4", SenderID: "1", Name: "Alex", Text: "new year", TimeSent: sent(2020, 1, 1, 9), FavoriteBy: []string{"2", "3"}},
+		{MessageID: "3", SenderID: "2", Name: "Sam", Text: "best", TimeSent: sent(2019, 7, 4, 20), FavoriteBy: []string{"1", "3", "4"}},
+		{MessageID: "2", SenderID: "bot", Name: "MemsBot", Text: "a repost", TimeSent: sent(2019, 7, 4, 21), SenderType: "bot", FavoriteBy: []string{"1"}},
+		{MessageID: "1", SenderID: "1", Name: "Alexander", Text: "first", TimeSent: sent(2019, 7, 3, 20), FavoriteBy: []string{"2"}},
+	}
+	names := map[string]string{"1": "Alex", "2": "Sam", "3": "Jo", "4": "Kim", "5": "Quiet"}
+
+	stats := computeGroupStats(messages, names, time.UTC, time.Time{}, time.Time{})
+	if stats.Messages != 3 || stats.Likes != 6 {
+		t.Errorf("counted %d messages and %d likes, want 3 and 6 without the bot", stats.Messages, stats.Likes)
+	}
+	if len(stats.Members) != 5 || stats.Members[0].Name != "Alex" || stats.Members[0].LikesReceived != 3 || stats.Members[0].Messages != 2 {
+		t.Errorf("members are %+v, want Alex first with 3 likes over 2 messages", stats.Members)
+	}
+	if stats.TopMessage == nil || stats.TopMessage.MessageID != "3" {
+		t.Errorf("top message is %+v, want Sam's", stats.TopMessage)
+	}
+	if stats.LikesByHour[20] != 4 || stats.MessagesByWeekday["Thursday"] != 1 {
+		t.Errorf("by hour %v and by weekday %v", stats.LikesByHour, stats.MessagesByWeekday)
+	}
+	for _, member := range stats.Members {
+		if member.Name == "Jo" && member.LikesGiven != 2 {
+			t.Errorf("Jo gave %d likes, want 2", member.LikesGiven)
+		}
+	}
+
+	from, to, err := parseStatsRange("2019-07-04", "2019-12-31", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats = computeGroupStats(messages, names, time.UTC, from, to)
+	if stats.Messages != 1 || stats.TopMessage.MessageID != "3" || stats.From != "2019-07-04" || stats.To != "2019-12-31" {
+		t.Errorf("2019-07-04 to 2019-12-31 got %+v", stats)
+	}
+}
+
+func TestFormatStats(t *testing.T) {
+	stats := computeGroupStats([]*Message{
+		{MessageID: "2", SenderID: "2", Name: "Sam", Text: "best", TimeSent: time.Date(2019, 7, 4, 20, 0, 0, 0, time.UTC).Unix(), FavoriteBy: []string{"1", "3"}},
+		{MessageID: "1", SenderID: "1", Name: "Alex", Text: "first", TimeSent: time.Date(2019, 7, 3, 9, 0, 0, 0, time.UTC).Unix(), FavoriteBy: []string{"2"}},
+	}, nil, time.UTC, time.Time{}, time.Time{})
+	text := formatStats(stats)
+	for _, line := range []string{
+		"Stats for all time: 2 messages, ❤️x3",
+		"Most liked: Sam ❤️x2, Alex ❤️x1",
+		"Best hours: 8pm, 9am",
+		"Top message: \"best\" - Sam, 2019-07-04 ❤️x2",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("stats are missing %q:\n%s", line, text)
+		}
+	}
+	if empty := formatStats(computeGroupStats(nil, nil, time.UTC, time.Time{}, time.Time{})); empty != "Stats for all time: 0 messages, ❤️x0" {
+		t.Errorf("empty stats are %q", empty)
+	}
+}
+
+func TestStatsCommandBeforeTheFirstSync(t *testing.T) {
+	newTestGroup(t, "g1", "a", "b")
+	reply, err := dispatchCommand(Message{GroupID: "g1", Text: "!stats"})
+	if reply != notArchivedReply || err != nil {
+		t.Errorf("!stats before the first sync got %q, %v", reply, err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"GroupMeChatBot/config"
+	"fmt"
+	"log"
+	"time"
+)
+
+//groupSettings is the group's config with its stored time zone, if it has one, taking the place of the configured location.
+//Dates are matched and formatted in the resulting Loc, so "on this day" is the group's own day
+func groupSettings(groupID string) config.GroupConfig {
+	groupConfig := appConfig.ForGroup(groupID)
+	item, ok, err := store.GetItem(groupID)
+	if err != nil {
+		log.Print(fmt.Sprintf("Error reached when getting group %s's time zone, using the configured location.", groupID))
+		log.Print(err)
+	}
+	if ok && item.TimeZone != "" {
+		groupConfig.Location = item.TimeZone
+	}
+	return groupConfig
+}
+
+//setTimeZone stores an IANA time zone like America/Los_Angeles for the group, or clears it when zone is empty
+func setTimeZone(groupID, zone string) error {
+	item, ok, err := store.GetItem(groupID)
+	if err != nil {
+		return err
+	}
+	if !ok || item.BotId == "" {
+		return errNoBotInGroup
+	}
+	if zone != "" {
+		if _, err := time.LoadLocation(zone); err != nil {
+			return fmt.Errorf("unknown time zone %q, use an IANA name like America/Los_Angeles", zone)
+		}
+	}
+	item.TimeZone = zone
+	return store.SaveItem(item)
+}
+
+func timeZoneMenu(groups []Group) {
+	fmt.Println("\n\nHere are all the groups you are a member of. Enter the number corresponding to the group whose time zone you want to set: ")
+	groupIndex := menuHelper(groups)
+	if groupIndex < 0 {
+		return
+	}
+	groupID := groups[groupIndex].GroupID
+	answer := promptMenu(fmt.Sprintf("IANA time zone, like America/Los_Angeles, or - to use the configured one [%s]:", groupSettings(groupID).Location))
+	if answer == "" {
+		return
+	}
+	if answer == "-" {
+		answer = ""
+	}
+	err := setTimeZone(groupID, answer)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(fmt.Sprintf("Saved time zone: %s", groupSettings(groupID).Location))
+}